@@ -1,10 +1,12 @@
 package main
 
 import (
+	"os"
+
 	// Import your internal package using the full module path
-	"github.com/2004-nikhil/quicknotes/internal/tui" 
+	"github.com/2004-nikhil/quicknotes/internal/cli"
 )
 
 func main() {
-	tui.Run()
-}
\ No newline at end of file
+	os.Exit(cli.Run(os.Args[1:]))
+}
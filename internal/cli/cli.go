@@ -0,0 +1,249 @@
+// Package cli implements the quicknotes command line. Without arguments it
+// starts the TUI; subcommands work on the same notebook without it, for use
+// from scripts, git hooks and cron jobs.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/2004-nikhil/quicknotes/internal/config"
+	"github.com/2004-nikhil/quicknotes/internal/store"
+	"github.com/2004-nikhil/quicknotes/internal/tui"
+)
+
+// Output destinations, replaceable for testing.
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// session is what a subcommand runs against.
+type session struct {
+	config config.Config
+	store  store.Store
+}
+
+// command is a single quicknotes subcommand.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(s *session, args []string) error
+}
+
+var commands = []command{
+	{"add", "add --title TITLE [--folder FOLDER] [--tag TAG]... [--content TEXT]", "Create a note", runAdd},
+	{"capture", "capture [--title TITLE] [--folder FOLDER] [--tag TAG]... [--template NAME] < INPUT", "Create a note from standard input", runCapture},
+	{"list", "list [--folder FOLDER] [--tag TAG] [--format table|json|ndjson] [--with-content]", "List notes", runList},
+	{"show", "show [--format table|json|ndjson] ID", "Print a note", runShow},
+	{"search", "search [--fuzzy] [--format table|json|ndjson] [--with-content] QUERY", "Search titles, content and tags", runSearch},
+	{"rm", "rm [--permanent] ID...", "Move notes to the trash", runRm},
+	{"trash", "trash [--format table|json|ndjson] [--with-content]", "List notes in the trash", runTrash},
+	{"restore", "restore ID...", "Restore notes from the trash", runRestore},
+	{"tag", "tag ID [+]TAG|-TAG...", "Add or remove tags on a note", runTag},
+	{"mv", "mv ID FOLDER", "Move a note to another folder", runMv},
+	{"vaults", "vaults", "List vaults", runVaults},
+}
+
+// errUsage signals that the arguments were wrong; the usage is printed.
+var errUsage = errors.New("invalid usage")
+
+// Run executes the command line and returns the process exit code.
+func Run(args []string) int {
+	global := flag.NewFlagSet("quicknotes", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { printUsage(stderr) }
+	configPath := global.String("config", "", "config file (default "+config.DefaultPath()+")")
+	dataDir := global.String("data-dir", "", "directory holding the default vault")
+	vaultName := global.String("vault", "", "vault to open")
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	args = global.Args()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "quicknotes: %v\n", err)
+		return 1
+	}
+	if *dataDir != "" {
+		cfg.DataDir = config.ExpandHome(*dataDir)
+	}
+	if *vaultName != "" {
+		cfg.Vault = *vaultName
+	}
+	vault, err := cfg.FindVault(cfg.Vault)
+	if err != nil {
+		fmt.Fprintf(stderr, "quicknotes: %v\n", err)
+		return 1
+	}
+	st, err := openStore(cfg, vault)
+	if err != nil {
+		fmt.Fprintf(stderr, "quicknotes: %v\n", err)
+		return 1
+	}
+	if c, ok := st.(io.Closer); ok {
+		defer c.Close()
+	}
+	s := &session{config: cfg, store: st}
+
+	if len(args) == 0 {
+		opts := tui.Options{
+			DefaultFolder:  cfg.DefaultFolder,
+			Theme:          cfg.Theme,
+			Colors:         cfg.Colors,
+			Editor:         cfg.Editor,
+			Vault:          vault.Name,
+			Vaults:         vaultSwitcher{cfg},
+			TrashRetention: cfg.TrashRetention(),
+		}
+		if err := tui.Run(s.store, opts); err != nil {
+			fmt.Fprintf(stderr, "quicknotes: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(stdout)
+		return 0
+	}
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(s, args[1:])
+		var conflict *store.ConflictError
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(stderr, "usage: quicknotes %s\n", cmd.usage)
+			return 2
+		case errors.As(err, &conflict):
+			fmt.Fprintf(stderr, "quicknotes: warning: %v\n", err)
+			return 0
+		default:
+			fmt.Fprintf(stderr, "quicknotes: %v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(stderr, "quicknotes: unknown command %q\n\n", name)
+	printUsage(stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: quicknotes [--config FILE] [--data-dir DIR] [--vault NAME] [COMMAND]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  quicknotes                 Start the interactive interface")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  quicknotes %-15s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'quicknotes COMMAND -h' for the options of a command.")
+}
+
+// loadConfig reads the config file named by --config, $QUICKNOTES_CONFIG
+// or the default location, in that order.
+func loadConfig(path string) (config.Config, error) {
+	if path == "" {
+		path = os.Getenv("QUICKNOTES_CONFIG")
+	}
+	if path != "" {
+		return config.Load(path, true)
+	}
+	return config.Load(config.DefaultPath(), false)
+}
+
+// openStore returns the store holding the vault's notebook.
+func openStore(cfg config.Config, vault config.Vault) (store.Store, error) {
+	return store.Open(cfg.Backend, vault.Dir, cfg.Seed())
+}
+
+// vaultSwitcher gives the TUI access to the configured vaults.
+type vaultSwitcher struct {
+	cfg config.Config
+}
+
+func (v vaultSwitcher) Names() []string {
+	var names []string
+	for _, vault := range v.cfg.Vaults() {
+		names = append(names, vault.Name)
+	}
+	return names
+}
+
+func (v vaultSwitcher) Open(name string) (store.Store, error) {
+	vault, err := v.cfg.FindVault(name)
+	if err != nil {
+		return nil, err
+	}
+	return openStore(v.cfg, vault)
+}
+
+func (v vaultSwitcher) Create(name string) error {
+	_, err := v.cfg.CreateVault(name)
+	return err
+}
+
+// load reads the notebook, explaining how to recover a damaged one. Notes
+// past the trash retention period are dropped and broken references are
+// repaired; that is saved along with the command's own changes.
+func (s *session) load() (*store.AppData, error) {
+	data, err := s.store.Load()
+	var corrupt *store.CorruptError
+	if errors.As(err, &corrupt) {
+		return nil, fmt.Errorf("%v; run quicknotes without arguments to recover it", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if w, ok := s.store.(store.Warner); ok {
+		for _, warning := range w.Warnings() {
+			fmt.Fprintf(stderr, "quicknotes: warning: %s\n", warning)
+		}
+	}
+	data.PurgeTrash(s.config.TrashRetention())
+	data.Repair(s.config.DefaultFolder)
+	return data, nil
+}
+
+// parseID parses a note ID given on the command line.
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid note ID %q", s)
+	}
+	return id, nil
+}
+
+// findNote returns the note with the given ID.
+func findNote(data *store.AppData, id int) (*store.Note, error) {
+	for i := range data.Notes {
+		if data.Notes[i].ID == id {
+			return &data.Notes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("note %d not found", id)
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,419 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/2004-nikhil/quicknotes/internal/search"
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+// newFlagSet returns a flag set that reports errors instead of exiting.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parseFlags parses args, mapping every parse failure to errUsage.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+func runAdd(s *session, args []string) error {
+	fs := newFlagSet("add")
+	title := fs.String("title", "", "note title")
+	folder := fs.String("folder", "", "folder to file the note in (default: the notebook's default folder)")
+	content := fs.String("content", "", "note content")
+	var tags stringList
+	fs.Var(&tags, "tag", "tag to add (repeatable)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*title) == "" || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	if *folder == "" {
+		*folder = data.DefaultFolder
+	}
+	note := data.NewNote(strings.TrimSpace(*title), *folder)
+	note.Content = *content
+	return createNote(s, data, note, tags)
+}
+
+func runCapture(s *session, args []string) error {
+	fs := newFlagSet("capture")
+	title := fs.String("title", "", "note title (default: time of capture)")
+	folder := fs.String("folder", "", "folder to file the note in (default: the notebook's default folder)")
+	templateName := fs.String("template", "", "template to start the note from")
+	var tags stringList
+	fs.Var(&tags, "tag", "tag to add (repeatable)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errUsage
+	}
+
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return fmt.Errorf("nothing to capture; pipe content in, e.g. 'dmesg | quicknotes capture'")
+	}
+	captured, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(*title) == "" {
+		*title = "Captured " + time.Now().Format("2006-01-02 15:04")
+	}
+	if *folder == "" {
+		*folder = data.DefaultFolder
+	}
+	note := data.NewNote(strings.TrimSpace(*title), *folder)
+	note.Content = string(captured)
+	if *templateName != "" {
+		template, err := findTemplate(data, *templateName)
+		if err != nil {
+			return err
+		}
+		note.Content = strings.TrimRight(template.Content, "\n") + "\n\n" + note.Content
+		tags = slices.Concat(template.Tags, tags)
+	}
+	return createNote(s, data, note, tags)
+}
+
+func runList(s *session, args []string) error {
+	fs := newFlagSet("list")
+	folder := fs.String("folder", "", "only list notes in this folder and its subfolders")
+	tag := fs.String("tag", "", "only list notes with this tag")
+	out := addOutputFlags(fs, false)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errUsage
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	var notes []store.Note
+	for _, note := range data.LiveNotes() {
+		if *folder != "" && !store.InFolder(note.Folder, *folder) {
+			continue
+		}
+		if *tag != "" && !slices.Contains(note.Tags, *tag) {
+			continue
+		}
+		notes = append(notes, note)
+	}
+	return out.writeNotes(notes)
+}
+
+func runShow(s *session, args []string) error {
+	fs := newFlagSet("show")
+	out := addOutputFlags(fs, true)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	id, err := parseID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	note, err := findNote(data, id)
+	if err != nil {
+		return err
+	}
+	return out.writeNote(*note)
+}
+
+func runSearch(s *session, args []string) error {
+	fs := newFlagSet("search")
+	fuzzy := fs.Bool("fuzzy", false, "tolerate typos; the query is taken as plain text")
+	out := addOutputFlags(fs, false)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errUsage
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+	if *fuzzy {
+		return out.writeNotes(search.Fuzzy(nil, data.LiveNotes(), query))
+	}
+	results, err := search.Ranked(nil, data.LiveNotes(), query)
+	if err != nil {
+		return err
+	}
+	return out.writeNotes(results)
+}
+
+func runRm(s *session, args []string) error {
+	fs := newFlagSet("rm")
+	permanent := fs.Bool("permanent", false, "delete for good instead of moving to the trash")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errUsage
+	}
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	ids, err := parseNoteIDs(data, fs.Args())
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if *permanent {
+			data.DeleteNote(id)
+		} else {
+			data.TrashNote(id)
+		}
+	}
+	if err := s.store.Save(data); err != nil {
+		return err
+	}
+	if *permanent {
+		fmt.Fprintf(stdout, "Deleted %d note(s)\n", len(ids))
+	} else {
+		fmt.Fprintf(stdout, "Moved %d note(s) to the trash\n", len(ids))
+	}
+	return nil
+}
+
+func runTrash(s *session, args []string) error {
+	fs := newFlagSet("trash")
+	out := addOutputFlags(fs, false)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errUsage
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	return out.writeNotes(data.TrashedNotes())
+}
+
+func runRestore(s *session, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	ids, err := parseNoteIDs(data, args)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !data.RestoreNote(id) {
+			return fmt.Errorf("note %d is not in the trash", id)
+		}
+	}
+	if err := s.store.Save(data); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Restored %d note(s)\n", len(ids))
+	return nil
+}
+
+func runTag(s *session, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	found, err := findNote(data, id)
+	if err != nil {
+		return err
+	}
+
+	note := *found
+	note.Tags = slices.Clone(note.Tags)
+	for _, arg := range args[1:] {
+		if tag, ok := strings.CutPrefix(arg, "-"); ok {
+			note.Tags = slices.DeleteFunc(note.Tags, func(t string) bool { return t == tag })
+		} else {
+			note.Tags = addTag(data, note.Tags, strings.TrimPrefix(arg, "+"))
+		}
+	}
+	note.UpdatedAt = time.Now()
+	data.RecordEdit(note)
+	if err := s.store.Save(data); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Note %d tags: %s\n", note.ID, strings.Join(note.Tags, ", "))
+	return nil
+}
+
+func runMv(s *session, args []string) error {
+	if len(args) != 2 || strings.TrimSpace(args[1]) == "" {
+		return errUsage
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	found, err := findNote(data, id)
+	if err != nil {
+		return err
+	}
+
+	note := *found
+	if note.Folder, err = addFolder(data, args[1]); err != nil {
+		return err
+	}
+	note.UpdatedAt = time.Now()
+	data.RecordEdit(note)
+	if err := s.store.Save(data); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Moved note %d to %s\n", note.ID, note.Folder)
+	return nil
+}
+
+func runVaults(s *session, args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for _, vault := range s.config.Vaults() {
+		marker := " "
+		if vault.Name == s.config.Vault {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %s\t%s\n", marker, vault.Name, vault.Dir)
+	}
+	return w.Flush()
+}
+
+// parseNoteIDs parses note IDs given on the command line, checking that
+// each note exists.
+func parseNoteIDs(data *store.AppData, args []string) ([]int, error) {
+	var ids []int
+	for _, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := findNote(data, id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// createNote tags a new note, adds it to the notebook and saves.
+func createNote(s *session, data *store.AppData, note store.Note, tags []string) error {
+	for _, tag := range tags {
+		note.Tags = addTag(data, note.Tags, tag)
+	}
+	folder, err := addFolder(data, note.Folder)
+	if err != nil {
+		return err
+	}
+	note.Folder = folder
+	data.Notes = append(data.Notes, note)
+	data.AddRevision(note)
+	if err := s.store.Save(data); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Created note %d\n", note.ID)
+	return nil
+}
+
+// findTemplate returns the template with the given name, ignoring case.
+func findTemplate(data *store.AppData, name string) (store.Template, error) {
+	for _, template := range data.Templates {
+		if strings.EqualFold(template.Name, name) {
+			return template, nil
+		}
+	}
+	return store.Template{}, fmt.Errorf("template %q not found", name)
+}
+
+// addTag adds tag to tags, registering it in the notebook if it is new.
+func addTag(data *store.AppData, tags []string, tag string) []string {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || slices.Contains(tags, tag) {
+		return tags
+	}
+	if !slices.Contains(data.Tags, tag) {
+		data.Tags = append(data.Tags, tag)
+	}
+	return append(tags, tag)
+}
+
+// addFolder registers folder, and its parents if it is nested, in the
+// notebook if it is new and returns its cleaned path.
+func addFolder(data *store.AppData, folder string) (string, error) {
+	folder, err := store.CleanFolderPath(folder)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(data.Folders, folder) {
+		if err := data.AddFolder(folder); err != nil {
+			return "", err
+		}
+	}
+	return folder, nil
+}
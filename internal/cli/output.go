@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+// Output formats accepted by --format.
+const (
+	formatTable  = "table"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// outputFlags are the options shared by commands that print notes.
+type outputFlags struct {
+	format      string
+	withContent bool
+}
+
+func addOutputFlags(fs *flag.FlagSet, withContent bool) *outputFlags {
+	o := &outputFlags{}
+	fs.StringVar(&o.format, "format", formatTable, "output format: table, json or ndjson")
+	fs.BoolVar(&o.withContent, "with-content", withContent, "include note content in json/ndjson output")
+	return o
+}
+
+func (o *outputFlags) validate() error {
+	switch o.format {
+	case formatTable, formatJSON, formatNDJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (want table, json or ndjson)", o.format)
+}
+
+// noteRecord is the JSON form of a note. Content is only present when
+// requested.
+type noteRecord struct {
+	store.Note
+	Content *string `json:"content,omitempty"`
+}
+
+func (o *outputFlags) record(note store.Note) noteRecord {
+	r := noteRecord{Note: note}
+	if o.withContent {
+		r.Content = &note.Content
+	}
+	return r
+}
+
+// writeNotes prints notes in the requested format.
+func (o *outputFlags) writeNotes(notes []store.Note) error {
+	switch o.format {
+	case formatJSON:
+		records := make([]noteRecord, 0, len(notes))
+		for _, note := range notes {
+			records = append(records, o.record(note))
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case formatNDJSON:
+		enc := json.NewEncoder(stdout)
+		for _, note := range notes {
+			if err := enc.Encode(o.record(note)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tDETAILS")
+	for _, note := range notes {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", note.ID, note.Title, note.Summary())
+	}
+	return w.Flush()
+}
+
+// writeNote prints a single note in the requested format.
+func (o *outputFlags) writeNote(note store.Note) error {
+	switch o.format {
+	case formatJSON:
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(o.record(note))
+	case formatNDJSON:
+		return json.NewEncoder(stdout).Encode(o.record(note))
+	}
+
+	fmt.Fprintf(stdout, "# %s\n", note.Title)
+	fmt.Fprintf(stdout, "ID:      %d\n", note.ID)
+	fmt.Fprintf(stdout, "Folder:  %s\n", note.Folder)
+	fmt.Fprintf(stdout, "Tags:    %s\n", strings.Join(note.Tags, ", "))
+	fmt.Fprintf(stdout, "Created: %s\n", note.CreatedAt.Format(time.DateTime))
+	fmt.Fprintf(stdout, "Updated: %s\n", note.UpdatedAt.Format(time.DateTime))
+	if note.Content != "" {
+		fmt.Fprintf(stdout, "\n%s\n", strings.TrimRight(note.Content, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Package config loads QuickNotes settings from the config file and
+// QUICKNOTES_* environment variables.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+// Config holds the user's settings. Precedence, lowest first: built-in
+// defaults, the config file, QUICKNOTES_* environment variables and
+// command line flags.
+type Config struct {
+	// DataDir is the directory holding data.json of the default vault.
+	DataDir string `toml:"data_dir"`
+	// Backend selects how notebooks are stored: "json", "markdown" or "sqlite".
+	Backend string `toml:"backend"`
+	// Vault is the vault opened at startup; VaultDirs maps the names of
+	// extra vaults to their data directories.
+	Vault     string            `toml:"vault"`
+	VaultDirs map[string]string `toml:"vaults"`
+	// DefaultFolder is the default folder of a newly created notebook, and
+	// of notebooks written before the default folder was recorded in them.
+	// After that the notebook's own default folder is used.
+	DefaultFolder string `toml:"default_folder"`
+	// DefaultFolders and DefaultTags seed a newly created notebook.
+	DefaultFolders []string `toml:"default_folders"`
+	DefaultTags    []string `toml:"default_tags"`
+	// Theme names a built-in color palette; Colors overrides single colors
+	// of it by name (primary, secondary, accent, success, warning, error,
+	// text, subtle).
+	Theme  string            `toml:"theme"`
+	Colors map[string]string `toml:"colors"`
+	// Editor is the external editor command, overriding $VISUAL/$EDITOR.
+	Editor string `toml:"editor"`
+	// TrashRetentionDays is how long deleted notes stay in the trash before
+	// they are purged for good; 0 keeps them until deleted by hand.
+	TrashRetentionDays int `toml:"trash_retention_days"`
+}
+
+// Default returns the settings used when nothing is configured.
+func Default() Config {
+	seed := store.DefaultData()
+	return Config{
+		DataDir:            filepath.Dir(store.DefaultPath()),
+		Backend:            store.BackendJSON,
+		Vault:              DefaultVault,
+		DefaultFolder:      "General",
+		DefaultFolders:     seed.Folders,
+		DefaultTags:        seed.Tags,
+		Theme:              "default",
+		TrashRetentionDays: 30,
+	}
+}
+
+// DefaultPath returns the config file location:
+// $XDG_CONFIG_HOME/quicknotes/config.toml, falling back to ~/.config (or
+// the platform config directory on Windows).
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" && runtime.GOOS == "windows" {
+		dir, _ = os.UserConfigDir()
+	}
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "quicknotes", "config.toml")
+}
+
+// Load returns the defaults overlaid with the config file at path and the
+// environment. A missing file is only an error if required is set, i.e.
+// the path was given explicitly.
+func Load(path string, required bool) (Config, error) {
+	cfg := Default()
+	meta, err := toml.DecodeFile(path, &cfg)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && !required:
+	case err != nil:
+		return cfg, fmt.Errorf("reading config: %w", err)
+	default:
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return cfg, fmt.Errorf("%s: unknown setting %q", path, undecoded[0].String())
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	if cfg.TrashRetentionDays < 0 {
+		return cfg, fmt.Errorf("trash_retention_days must not be negative")
+	}
+	cfg.DataDir = ExpandHome(cfg.DataDir)
+	return cfg, nil
+}
+
+// applyEnv overrides settings from QUICKNOTES_* environment variables.
+func (c *Config) applyEnv() error {
+	if v := os.Getenv("QUICKNOTES_DATA_DIR"); v != "" {
+		c.DataDir = v
+	}
+	if v := os.Getenv("QUICKNOTES_BACKEND"); v != "" {
+		c.Backend = v
+	}
+	if v := os.Getenv("QUICKNOTES_VAULT"); v != "" {
+		c.Vault = v
+	}
+	if v := os.Getenv("QUICKNOTES_DEFAULT_FOLDER"); v != "" {
+		c.DefaultFolder = v
+	}
+	if v := os.Getenv("QUICKNOTES_DEFAULT_FOLDERS"); v != "" {
+		c.DefaultFolders = splitList(v)
+	}
+	if v := os.Getenv("QUICKNOTES_DEFAULT_TAGS"); v != "" {
+		c.DefaultTags = splitList(v)
+	}
+	if v := os.Getenv("QUICKNOTES_THEME"); v != "" {
+		c.Theme = v
+	}
+	if v := os.Getenv("QUICKNOTES_EDITOR"); v != "" {
+		c.Editor = v
+	}
+	if v := os.Getenv("QUICKNOTES_TRASH_RETENTION_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("QUICKNOTES_TRASH_RETENTION_DAYS: %q is not a number of days", v)
+		}
+		c.TrashRetentionDays = days
+	}
+	return nil
+}
+
+// TrashRetention is how long deleted notes stay in the trash; zero means
+// forever.
+func (c Config) TrashRetention() time.Duration {
+	return time.Duration(c.TrashRetentionDays) * 24 * time.Hour
+}
+
+// Seed returns the notebook to create when none exists yet.
+func (c Config) Seed() *store.AppData {
+	data := store.DefaultData()
+	data.Folders = append([]string{}, c.DefaultFolders...)
+	data.Tags = append([]string{}, c.DefaultTags...)
+	if c.DefaultFolder != "" && !slices.Contains(data.Folders, c.DefaultFolder) {
+		data.Folders = append([]string{c.DefaultFolder}, data.Folders...)
+	}
+	if c.DefaultFolder != "" {
+		data.DefaultFolder = c.DefaultFolder
+	} else if len(data.Folders) > 0 {
+		data.DefaultFolder = data.Folders[0]
+	}
+	return data
+}
+
+// ExpandHome replaces a leading "~" with the user's home directory, for
+// paths that did not go through a shell, such as --data-dir=~/notes.
+func ExpandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
+
+// splitList splits a comma separated environment value.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
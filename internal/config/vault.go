@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultVault names the notebook kept directly in DataDir.
+const DefaultVault = "default"
+
+// Vault is a named notebook with its own data directory, and so its own
+// notes, folders, tags and templates.
+type Vault struct {
+	Name string
+	Dir  string
+}
+
+// vaultsDir holds vaults created from QuickNotes itself.
+func (c Config) vaultsDir() string {
+	return filepath.Join(ExpandHome(c.DataDir), "vaults")
+}
+
+// Vaults lists the default vault followed by those configured under
+// [vaults] and those found in <data_dir>/vaults, sorted by name.
+func (c Config) Vaults() []Vault {
+	dirs := map[string]string{}
+	if entries, err := os.ReadDir(c.vaultsDir()); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				dirs[entry.Name()] = filepath.Join(c.vaultsDir(), entry.Name())
+			}
+		}
+	}
+	for name, dir := range c.VaultDirs {
+		dirs[name] = ExpandHome(dir)
+	}
+	delete(dirs, DefaultVault)
+
+	vaults := []Vault{{Name: DefaultVault, Dir: ExpandHome(c.DataDir)}}
+	for name, dir := range dirs {
+		vaults = append(vaults, Vault{Name: name, Dir: dir})
+	}
+	sort.Slice(vaults[1:], func(i, j int) bool {
+		return vaults[i+1].Name < vaults[j+1].Name
+	})
+	return vaults
+}
+
+// FindVault returns the vault with the given name.
+func (c Config) FindVault(name string) (Vault, error) {
+	for _, vault := range c.Vaults() {
+		if vault.Name == name {
+			return vault, nil
+		}
+	}
+	return Vault{}, fmt.Errorf("unknown vault %q", name)
+}
+
+// CreateVault makes a new vault under <data_dir>/vaults.
+func (c Config) CreateVault(name string) (Vault, error) {
+	if name == "" || name == "." || name == ".." || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\:`) {
+		return Vault{}, fmt.Errorf("invalid vault name %q", name)
+	}
+	if _, err := c.FindVault(name); err == nil {
+		return Vault{}, fmt.Errorf("vault %q already exists", name)
+	}
+	dir := filepath.Join(c.vaultsDir(), name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Vault{}, err
+	}
+	return Vault{Name: name, Dir: dir}, nil
+}
@@ -0,0 +1,142 @@
+// Package diff compares two versions of a note line by line.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+// op is one line of an edit script.
+type op struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// Unified returns the differences between from and to in unified diff
+// format, labelling the two sides fromName and toName. It returns the
+// empty string if the texts are equal.
+func Unified(from, to, fromName, toName string) string {
+	if from == to {
+		return ""
+	}
+	ops := lineDiff(splitLines(from), splitLines(to))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(ops); {
+		// Find the next change and the run of ops its hunk covers.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		lo := max(first-context, start)
+		hi := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				hi = i + 1
+			} else if i-hi >= 2*context {
+				break
+			}
+		}
+		hi = min(hi+context, len(ops))
+		writeHunk(&b, ops, lo, hi)
+		start = hi
+	}
+	return b.String()
+}
+
+// writeHunk writes ops[lo:hi] with its @@ header.
+func writeHunk(b *strings.Builder, ops []op, lo, hi int) {
+	fromLine, toLine := 1, 1
+	for _, o := range ops[:lo] {
+		if o.kind != '+' {
+			fromLine++
+		}
+		if o.kind != '-' {
+			toLine++
+		}
+	}
+	var fromCount, toCount int
+	for _, o := range ops[lo:hi] {
+		if o.kind != '+' {
+			fromCount++
+		}
+		if o.kind != '-' {
+			toCount++
+		}
+	}
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+	for _, o := range ops[lo:hi] {
+		fmt.Fprintf(b, "%c%s\n", o.kind, o.text)
+	}
+}
+
+// hunkRange formats the start and length of one side of a hunk. An empty
+// range names the line before it, as diff(1) does.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// lineDiff returns the shortest edit script turning a into b, found from
+// their longest common subsequence.
+func lineDiff(a, b []string) []op {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{'+', b[j]})
+	}
+	return ops
+}
+
+// splitLines splits text into lines, without a trailing empty line for a
+// final newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,151 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+	"github.com/sahilm/fuzzy"
+)
+
+// Fuzzy searches notes for query, tolerating typos. Titles and tags match
+// if they contain the letters of query in order, as in "mtng" for
+// "Meeting"; these matches come first, best first. They are followed by
+// notes containing every word of query, or words a few typos away from
+// them, ranked by relevance. The query is taken as plain text: filters and
+// operators are not recognised. ix must hold notes, or be nil to index
+// them on the spot.
+func Fuzzy(ix *Index, notes []store.Note, query string) []store.Note {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return notes
+	}
+	if ix == nil {
+		ix = NewIndex(notes)
+	}
+
+	// Best fuzzy score of each note over its title and tags
+	scores := map[int]int{}
+	score := func(id, s int) {
+		if best, ok := scores[id]; !ok || s > best {
+			scores[id] = s
+		}
+	}
+	titles := make([]string, len(notes))
+	var tags []string
+	for i, note := range notes {
+		titles[i] = note.Title
+		for _, tag := range note.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	for _, match := range fuzzy.Find(query, titles) {
+		score(notes[match.Index].ID, match.Score)
+	}
+	for _, match := range fuzzy.Find(query, tags) {
+		for _, note := range notes {
+			if slices.Contains(note.Tags, match.Str) {
+				score(note.ID, match.Score)
+			}
+		}
+	}
+
+	var results []store.Note
+	for _, note := range notes {
+		if _, ok := scores[note.ID]; ok {
+			results = append(results, note)
+		}
+	}
+	slices.SortStableFunc(results, func(a, b store.Note) int {
+		return cmp.Compare(scores[b.ID], scores[a.ID])
+	})
+
+	var ids []int
+	for _, hit := range ix.SearchTolerant(query) {
+		if _, ok := scores[hit.NoteID]; !ok {
+			ids = append(ids, hit.NoteID)
+		}
+	}
+	return append(results, pick(notes, ids)...)
+}
+
+// SearchTolerant is like Search, but a word also matches terms within a
+// few typos of it: one for words of four or five letters, two for longer
+// ones. Closer terms score higher. Typos are looked for after the first
+// letter only, which keeps the terms to compare with few, so "meetng"
+// finds "meeting" but "neeting" does not.
+func (ix *Index) SearchTolerant(query string) []Hit {
+	words := Tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+	var scores map[int]float64
+	for i, word := range words {
+		allowed := typosAllowed(word)
+		first, _ := utf8.DecodeRuneInString(word)
+		wordScores := map[int]float64{}
+		for _, term := range ix.withPrefix(string(first)) {
+			distance := 0
+			if !strings.HasPrefix(term, word) {
+				if distance = levenshtein(word, term, allowed); distance > allowed {
+					continue
+				}
+			}
+			for id, s := range ix.scoreTerm(ix.postings[term]) {
+				wordScores[id] = max(wordScores[id], s/float64(1+distance))
+			}
+		}
+		scores = intersect(scores, wordScores, i == 0)
+	}
+	return sortHits(scores)
+}
+
+// typosAllowed returns how many edits a word may be away from a term and
+// still match it. Short words must match exactly, as almost any other
+// short word is only an edit or two away.
+func typosAllowed(word string) int {
+	switch n := len([]rune(word)); {
+	case n >= 6:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// levenshtein returns the edit distance between a and b: the fewest
+// insertions, deletions and substitutions of characters turning one into
+// the other. It stops counting once the distance exceeds limit, returning
+// limit+1.
+func levenshtein(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, cur = cur, prev
+	}
+	return min(prev[len(rb)], limit+1)
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"kitten", "sitting", 5, 3},
+		{"flaw", "lawn", 2, 2},
+		{"same", "same", 0, 0},
+		{"", "abc", 3, 3},
+		{"héllo", "hello", 1, 1},
+		{"abc", "abd", 0, 1},
+		// Past the limit the distance is reported as limit+1
+		{"abcdef", "a", 2, 3},
+		{"kitten", "sitting", 2, 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("levenshtein(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSearchTolerant(t *testing.T) {
+	ix := NewIndex([]store.Note{
+		{ID: 1, Title: "Weekly meeting", Content: "Agenda for the team"},
+		{ID: 2, Title: "Release", Content: "Deployment steps for the report"},
+		{ID: 3, Title: "Draft", Content: "Repost the team photos"},
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"meetng", []int{1}},
+		{"deploymnt", []int{2}},
+		// Typos in the first letter are not looked for
+		{"neeting", nil},
+		// Short words must match exactly
+		{"tem", nil},
+		// Exact matches rank above close ones
+		{"report", []int{2, 3}},
+		{"teem agenda", []int{1}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := hitIDs(ix.SearchTolerant(tt.query)); !slices.Equal(got, tt.want) {
+			t.Errorf("SearchTolerant(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package search
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+// Fields of a note that are indexed, with how much a hit in each counts.
+// Title and tag hits outweigh hits in the body, as in the SQLite index.
+const (
+	fieldTitle = iota
+	fieldTags
+	fieldContent
+	numFields
+)
+
+var fieldWeights = [numFields]float64{fieldTitle: 10, fieldTags: 5, fieldContent: 1}
+
+// BM25 parameters: k1 limits how much repeating a term helps, b how much
+// long fields are penalised.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Index is an in-memory inverted index of notes, ranking matches with
+// BM25. It is built once and then kept current with Sync, which only
+// re-indexes notes that changed.
+type Index struct {
+	docs     map[int]*document
+	postings map[string]map[int]*[numFields]int // term -> note -> hits per field
+	totalLen [numFields]int                     // tokens per field, over all notes
+
+	// terms holds the keys of postings in order, so the terms starting
+	// with a word can be found by binary search. It is sorted on first
+	// use and kept in order from then on; nil until then.
+	terms []string
+}
+
+// document is what the index knows about one note.
+type document struct {
+	key    string // the indexed text, to tell whether the note changed
+	length [numFields]int
+	terms  []string
+}
+
+// Hit is a note matching a search, with its relevance score.
+type Hit struct {
+	NoteID int
+	Score  float64
+}
+
+// NewIndex indexes notes.
+func NewIndex(notes []store.Note) *Index {
+	ix := &Index{docs: map[int]*document{}, postings: map[string]map[int]*[numFields]int{}}
+	ix.Sync(notes)
+	return ix
+}
+
+// Sync updates the index to hold exactly notes: new and changed notes are
+// indexed again and notes that are gone are dropped.
+func (ix *Index) Sync(notes []store.Note) {
+	present := make(map[int]bool, len(notes))
+	for _, note := range notes {
+		present[note.ID] = true
+		key := note.Title + "\x00" + strings.Join(note.Tags, "\x00") + "\x00" + note.Content
+		if doc, ok := ix.docs[note.ID]; ok {
+			if doc.key == key {
+				continue
+			}
+			ix.remove(note.ID)
+		}
+		ix.add(note, key)
+	}
+	for id := range ix.docs {
+		if !present[id] {
+			ix.remove(id)
+		}
+	}
+}
+
+// Len returns how many notes are indexed.
+func (ix *Index) Len() int {
+	return len(ix.docs)
+}
+
+func (ix *Index) add(note store.Note, key string) {
+	doc := &document{key: key}
+	texts := [numFields]string{
+		fieldTitle:   note.Title,
+		fieldTags:    strings.Join(note.Tags, " "),
+		fieldContent: note.Content,
+	}
+	for field, text := range texts {
+		for _, term := range Tokenize(text) {
+			hits, ok := ix.postings[term]
+			if !ok {
+				hits = map[int]*[numFields]int{}
+				ix.postings[term] = hits
+				if ix.terms != nil {
+					i, _ := slices.BinarySearch(ix.terms, term)
+					ix.terms = slices.Insert(ix.terms, i, term)
+				}
+			}
+			counts, ok := hits[note.ID]
+			if !ok {
+				counts = &[numFields]int{}
+				hits[note.ID] = counts
+				doc.terms = append(doc.terms, term)
+			}
+			counts[field]++
+			doc.length[field]++
+		}
+		ix.totalLen[field] += doc.length[field]
+	}
+	ix.docs[note.ID] = doc
+}
+
+func (ix *Index) remove(id int) {
+	doc := ix.docs[id]
+	for _, term := range doc.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+			if i, ok := slices.BinarySearch(ix.terms, term); ok {
+				ix.terms = slices.Delete(ix.terms, i, i+1)
+			}
+		}
+	}
+	for field := range numFields {
+		ix.totalLen[field] -= doc.length[field]
+	}
+	delete(ix.docs, id)
+}
+
+// Search returns the notes containing every word of query, best first. A
+// word also matches longer words it is the start of, as in "dep" for
+// "deploy", and words are compared by their stem, so "plans" finds
+// "planning".
+func (ix *Index) Search(query string) []Hit {
+	words := Tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+	var scores map[int]float64
+	for i, word := range words {
+		scores = intersect(scores, ix.scoreWord(word), i == 0)
+	}
+	return sortHits(scores)
+}
+
+// Scores returns the relevance of every note matching at least one word
+// of query, for ranking results found by other means.
+func (ix *Index) Scores(query string) map[int]float64 {
+	scores := map[int]float64{}
+	for _, word := range Tokenize(query) {
+		for id, s := range ix.scoreWord(word) {
+			scores[id] += s
+		}
+	}
+	return scores
+}
+
+// scoreWord scores the notes containing word, or a term word is a prefix
+// of. A note containing several such terms scores by the best one.
+func (ix *Index) scoreWord(word string) map[int]float64 {
+	scores := map[int]float64{}
+	for _, term := range ix.withPrefix(word) {
+		for id, s := range ix.scoreTerm(ix.postings[term]) {
+			if term != word {
+				// Whole-word matches rank above prefix matches
+				s /= 2
+			}
+			scores[id] = max(scores[id], s)
+		}
+	}
+	return scores
+}
+
+// withPrefix returns the indexed terms starting with prefix, in order.
+func (ix *Index) withPrefix(prefix string) []string {
+	if ix.terms == nil {
+		ix.terms = make([]string, 0, len(ix.postings))
+		for term := range ix.postings {
+			ix.terms = append(ix.terms, term)
+		}
+		slices.Sort(ix.terms)
+	}
+	// The terms with a prefix sort together, from the prefix itself on
+	start, _ := slices.BinarySearch(ix.terms, prefix)
+	n := sort.Search(len(ix.terms)-start, func(i int) bool {
+		return !strings.HasPrefix(ix.terms[start+i], prefix)
+	})
+	return ix.terms[start : start+n]
+}
+
+// scoreTerm computes the BM25F score of one term for each note it is in.
+func (ix *Index) scoreTerm(hits map[int]*[numFields]int) map[int]float64 {
+	n := float64(len(ix.docs))
+	idf := math.Log(1 + (n-float64(len(hits))+0.5)/(float64(len(hits))+0.5))
+	scores := make(map[int]float64, len(hits))
+	for id, counts := range hits {
+		doc := ix.docs[id]
+		tf := 0.0
+		for field := range numFields {
+			if counts[field] == 0 {
+				continue
+			}
+			avg := float64(ix.totalLen[field]) / n
+			norm := 1 - bm25B + bm25B*float64(doc.length[field])/avg
+			tf += fieldWeights[field] * float64(counts[field]) / norm
+		}
+		scores[id] = idf * tf / (bm25K1 + tf)
+	}
+	return scores
+}
+
+// intersect adds the scores of the next word of a query to the scores of
+// the words before it, dropping notes that do not contain it. For the first
+// word, scores is nil and the word's scores are taken as they are.
+func intersect(scores, wordScores map[int]float64, first bool) map[int]float64 {
+	if first {
+		return wordScores
+	}
+	for id := range scores {
+		if s, ok := wordScores[id]; ok {
+			scores[id] += s
+		} else {
+			delete(scores, id)
+		}
+	}
+	return scores
+}
+
+// sortHits orders scored notes best first, and by ID between equals so
+// results are stable.
+func sortHits(scores map[int]float64) []Hit {
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{NoteID: id, Score: score})
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return a.NoteID - b.NoteID
+	})
+	return hits
+}
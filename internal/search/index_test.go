@@ -0,0 +1,70 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+func hitIDs(hits []Hit) []int {
+	var ids []int
+	for _, hit := range hits {
+		ids = append(ids, hit.NoteID)
+	}
+	return ids
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := NewIndex([]store.Note{
+		{ID: 1, Title: "Groceries", Content: "Remember the deploy checklist after shopping"},
+		{ID: 2, Title: "Deploy checklist", Content: "Tag the release", Tags: []string{"ops"}},
+		{ID: 3, Title: "Ideas", Content: "Planning the café menu", Tags: []string{"food"}},
+		{ID: 4, Title: "Trip", Content: "Planetarium visit next week"},
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// Title hits rank above content hits, and whole words above
+		// longer words they start
+		{"deploy", []int{2, 1}},
+		{"plan", []int{3, 4}},
+		{"deploy checklist", []int{2, 1}},
+		{"checkl", []int{2, 1}},
+		{"food", []int{3}},
+		{"plans", []int{3, 4}},
+		{"cafe", []int{3}},
+		{"ops release", []int{2}},
+		{"missing", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := hitIDs(ix.Search(tt.query)); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIndexSync(t *testing.T) {
+	notes := []store.Note{
+		{ID: 1, Title: "Alpha"},
+		{ID: 2, Title: "Beta"},
+	}
+	ix := NewIndex(notes)
+	if got := hitIDs(ix.Search("alpha")); !slices.Equal(got, []int{1}) {
+		t.Fatalf("Search(alpha) = %v, want [1]", got)
+	}
+
+	notes[0].Title = "Gamma"
+	notes = append(notes[:1], store.Note{ID: 3, Title: "Alphabet"})
+	ix.Sync(notes)
+	if ix.Len() != 2 {
+		t.Errorf("Len() = %d after Sync, want 2", ix.Len())
+	}
+	for query, want := range map[string][]int{"alpha": {3}, "gamma": {1}, "beta": nil} {
+		if got := hitIDs(ix.Search(query)); !slices.Equal(got, want) {
+			t.Errorf("Search(%q) after Sync = %v, want %v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+// Query is a parsed search query. Its syntax is:
+//
+//	deploy plan            notes containing both words
+//	"deploy plan"          notes containing the phrase
+//	tag:work               notes tagged work
+//	folder:Work            notes in Work or one of its subfolders
+//	title:"weekly sync"    notes whose title contains the phrase
+//	content:todo           notes whose content contains the word todo
+//	created:>2025-01-01    created after that day; also <, >=, <= and =
+//	updated:<7d            updated less than 7 days ago; also h, w, m, y
+//	a OR b, a AND b        either or both; terms next to each other are ANDed
+//	NOT a, -a              notes not matching a
+//	(a OR b) -c            parentheses group
+//
+// Words and field values are matched ignoring case. Words match as in
+// Index.Search, by stem and as prefixes, so "plans" finds "planning";
+// quoted phrases must appear as written. Operators are only recognised in
+// upper case, so "not" and "or" can still be searched for.
+type Query struct {
+	root node
+	now  time.Time
+}
+
+// node is one part of a parsed query.
+type node interface {
+	match(note store.Note, now time.Time) bool
+}
+
+type (
+	andNode struct{ left, right node }
+	orNode  struct{ left, right node }
+	notNode struct{ child node }
+	// termNode matches text in one field, or in the title, content and
+	// tags if field is "".
+	termNode struct {
+		field, value string
+		quoted       bool
+	}
+	// dateNode compares the created or updated time of a note with an
+	// absolute day or an age.
+	dateNode struct {
+		field, op string
+		day       time.Time     // set for absolute dates
+		age       time.Duration // set for relative dates
+	}
+)
+
+// fields are the field names a term can be prefixed with.
+var fields = []string{"tag", "folder", "title", "content", "created", "updated"}
+
+// Parse parses a search query. An empty query matches every note.
+func Parse(query string) (*Query, error) {
+	return parseAt(query, time.Now())
+}
+
+// parseAt parses query, resolving relative dates against now.
+func parseAt(query string, now time.Time) (*Query, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens, now: now}
+	q := &Query{now: now}
+	if len(tokens) == 0 {
+		return q, nil
+	}
+	if q.root, err = p.or(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s in query", p.tokens[p.pos])
+	}
+	return q, nil
+}
+
+// Match reports whether a note matches the query.
+func (q *Query) Match(note store.Note) bool {
+	return q.root == nil || q.root.match(note, q.now)
+}
+
+// Filter returns the notes matching the query, in their original order.
+func (q *Query) Filter(notes []store.Note) []store.Note {
+	var results []store.Note
+	for _, note := range notes {
+		if q.Match(note) {
+			results = append(results, note)
+		}
+	}
+	return results
+}
+
+// plainWords returns the words of a query that is only unquoted words to
+// be found anywhere in a note, and false for any other query.
+func (q *Query) plainWords() ([]string, bool) {
+	var words []string
+	var collect func(n node) bool
+	collect = func(n node) bool {
+		switch n := n.(type) {
+		case andNode:
+			return collect(n.left) && collect(n.right)
+		case termNode:
+			words = append(words, n.value)
+			return n.field == "" && !n.quoted
+		}
+		return false
+	}
+	if q.root == nil || !collect(q.root) {
+		return nil, false
+	}
+	return words, true
+}
+
+// rankWords returns the text of the query's words and phrases, outside
+// of negations and fields, to rank the notes it matches by.
+func (q *Query) rankWords() []string {
+	var words []string
+	var collect func(n node)
+	collect = func(n node) {
+		switch n := n.(type) {
+		case andNode:
+			collect(n.left)
+			collect(n.right)
+		case orNode:
+			collect(n.left)
+			collect(n.right)
+		case termNode:
+			if n.field == "" {
+				words = append(words, n.value)
+			}
+		}
+	}
+	collect(q.root)
+	return words
+}
+
+func (n andNode) match(note store.Note, now time.Time) bool {
+	return n.left.match(note, now) && n.right.match(note, now)
+}
+
+func (n orNode) match(note store.Note, now time.Time) bool {
+	return n.left.match(note, now) || n.right.match(note, now)
+}
+
+func (n notNode) match(note store.Note, now time.Time) bool {
+	return !n.child.match(note, now)
+}
+
+func (n termNode) match(note store.Note, _ time.Time) bool {
+	switch n.field {
+	case "tag":
+		return slices.ContainsFunc(note.Tags, func(tag string) bool { return strings.EqualFold(tag, n.value) })
+	case "folder":
+		return store.InFolder(strings.ToLower(note.Folder), n.value)
+	case "title":
+		if !n.quoted {
+			return containsWords(n.value, note.Title)
+		}
+		return strings.Contains(strings.ToLower(note.Title), n.value)
+	case "content":
+		if !n.quoted {
+			return containsWords(n.value, note.Content)
+		}
+		return strings.Contains(strings.ToLower(note.Content), n.value)
+	}
+	if !n.quoted {
+		return containsWords(n.value, note.Title, note.Content, strings.Join(note.Tags, " "))
+	}
+	return strings.Contains(strings.ToLower(note.Title), n.value) ||
+		strings.Contains(strings.ToLower(note.Content), n.value) ||
+		containsTag(note.Tags, n.value)
+}
+
+func (n dateNode) match(note store.Note, now time.Time) bool {
+	t := note.CreatedAt
+	if n.field == "updated" {
+		t = note.UpdatedAt
+	}
+	if n.age > 0 {
+		// Ages read as "less than 7 days ago" and "more than 7 days ago"
+		cutoff := now.Add(-n.age)
+		if n.op == ">" || n.op == ">=" {
+			return !t.After(cutoff)
+		}
+		return !t.Before(cutoff)
+	}
+	start, end := n.day, n.day.AddDate(0, 0, 1)
+	switch n.op {
+	case ">":
+		return !t.Before(end)
+	case ">=":
+		return !t.Before(start)
+	case "<":
+		return t.Before(start)
+	case "<=":
+		return t.Before(end)
+	}
+	return !t.Before(start) && t.Before(end)
+}
+
+// tokenKind is the kind of a query token.
+type tokenKind int
+
+const (
+	tokenTerm tokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+// token is one word, operator or parenthesis of a query.
+type token struct {
+	kind   tokenKind
+	field  string // for terms with a known field prefix
+	value  string
+	quoted bool
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenNot:
+		return "NOT"
+	case tokenOpen:
+		return `"("`
+	case tokenClose:
+		return `")"`
+	}
+	return strconv.Quote(t.value)
+}
+
+// lex splits a query into tokens.
+func lex(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose})
+			i++
+			continue
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, token{kind: tokenNot})
+			i++
+			continue
+		}
+
+		// A word runs to the next space or parenthesis outside quotes.
+		var text strings.Builder
+		field, quoted := "", false
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+			switch r := runes[i]; {
+			case r == '"':
+				end := slices.Index(runes[i+1:], '"')
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated quote in query")
+				}
+				text.WriteString(string(runes[i+1 : i+1+end]))
+				quoted = true
+				i += end + 2
+			case r == ':' && field == "" && !quoted && slices.Contains(fields, strings.ToLower(text.String())):
+				field = strings.ToLower(text.String())
+				text.Reset()
+				i++
+			default:
+				text.WriteRune(r)
+				i++
+			}
+		}
+		word := text.String()
+		switch {
+		case field == "" && !quoted && word == "AND":
+			tokens = append(tokens, token{kind: tokenAnd})
+		case field == "" && !quoted && word == "OR":
+			tokens = append(tokens, token{kind: tokenOr})
+		case field == "" && !quoted && word == "NOT":
+			tokens = append(tokens, token{kind: tokenNot})
+		default:
+			tokens = append(tokens, token{kind: tokenTerm, field: field, value: word, quoted: quoted})
+		}
+	}
+	return tokens, nil
+}
+
+// parser builds the query tree from tokens by recursive descent, with NOT
+// binding tightest, then AND, then OR.
+type parser struct {
+	tokens []token
+	pos    int
+	now    time.Time
+}
+
+// peek returns the kind of the next token, reporting false at the end.
+func (p *parser) peek() (tokenKind, bool) {
+	if p.pos >= len(p.tokens) {
+		return 0, false
+	}
+	return p.tokens[p.pos].kind, true
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if kind, ok := p.peek(); !ok || kind != tokenOr {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		kind, ok := p.peek()
+		if !ok || kind == tokenOr || kind == tokenClose {
+			return left, nil
+		}
+		if kind == tokenAnd {
+			p.pos++
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	kind, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("query ends unexpectedly")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch kind {
+	case tokenNot:
+		child, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child}, nil
+	case tokenOpen:
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if kind, ok := p.peek(); !ok || kind != tokenClose {
+			return nil, fmt.Errorf("missing \")\" in query")
+		}
+		p.pos++
+		return inner, nil
+	case tokenTerm:
+		return p.term(t)
+	}
+	return nil, fmt.Errorf("unexpected %s in query", t)
+}
+
+// term turns a term token into a node, parsing the value of date fields.
+func (p *parser) term(t token) (node, error) {
+	if t.field != "" && t.value == "" {
+		return nil, fmt.Errorf("%s: needs a value", t.field)
+	}
+	if t.field != "created" && t.field != "updated" {
+		value := strings.ToLower(t.value)
+		if t.field == "folder" {
+			value = strings.TrimSuffix(value, store.FolderSeparator)
+		}
+		return termNode{field: t.field, value: value, quoted: t.quoted}, nil
+	}
+
+	value := t.value
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(value, candidate); ok {
+			op, value = candidate, rest
+			break
+		}
+	}
+	if age, ok := parseAge(value); ok {
+		return dateNode{field: t.field, op: op, age: age}, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, p.now.Location())
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s: expected a date like 2025-01-31 or an age like 7d", t.field, t.value)
+	}
+	return dateNode{field: t.field, op: op, day: day}, nil
+}
+
+// parseAge parses an age such as 12h, 7d, 2w, 3m or 1y.
+func parseAge(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	day := 24 * time.Hour
+	units := map[byte]time.Duration{'h': time.Hour, 'd': day, 'w': 7 * day, 'm': 30 * day, 'y': 365 * day}
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
@@ -0,0 +1,165 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+// now is the time queries in these tests are parsed at.
+var now = time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 9, 0, 0, 0, time.UTC)
+}
+
+var queryNotes = []store.Note{
+	{ID: 1, Title: "Deploy plan", Content: "Roll out the new API", Tags: []string{"work", "ops"}, Folder: "Work/Infra", CreatedAt: day(3, 1), UpdatedAt: day(3, 14)},
+	{ID: 2, Title: "Groceries", Content: "milk, eggs, not much else", Tags: []string{"personal"}, Folder: "Personal", CreatedAt: day(1, 10), UpdatedAt: day(1, 10)},
+	{ID: 3, Title: "Weekly sync", Content: "deploy status and plan review", Tags: []string{"work", "meeting"}, Folder: "Work", CreatedAt: day(3, 10), UpdatedAt: day(3, 15)},
+	{ID: 4, Title: "Reading list", Content: "Books: Dune", Tags: []string{}, Folder: "Workshop", CreatedAt: time.Date(2024, 12, 31, 9, 0, 0, 0, time.UTC), UpdatedAt: day(2, 1)},
+}
+
+func TestQueryMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", []int{1, 2, 3, 4}},
+		{"deploy", []int{1, 3}},
+		{"DEPLOY plan", []int{1, 3}},
+		{`"deploy plan"`, []int{1}},
+		// Words match at the start of words, by stem; phrases anywhere
+		{"plans", []int{1, 3}},
+		{"ploy", nil},
+		{`"ploy"`, []int{1, 3}},
+		{"tag:work", []int{1, 3}},
+		{"TAG:Work", []int{1, 3}},
+		{"tag:wor", nil},
+		{"folder:Work", []int{1, 3}},
+		{"folder:work/", []int{1, 3}},
+		{"folder:Work/Infra", []int{1}},
+		{`title:"weekly sync"`, []int{3}},
+		{`title:"sync weekly"`, nil},
+		{"content:milk", []int{2}},
+		{"content:deploy", []int{3}},
+		{"content:revie", []int{3}},
+		{"title:weekl", []int{3}},
+
+		// Operators: NOT binds tightest, then AND, then OR
+		{"deploy OR groceries", []int{1, 2, 3}},
+		{"tag:personal OR tag:ops tag:work", []int{1, 2}},
+		{"tag:personal OR tag:ops AND tag:meeting", []int{2}},
+		{"(tag:personal OR tag:ops) tag:work", []int{1}},
+		{"tag:work -meeting", []int{1}},
+		{"tag:work NOT tag:meeting", []int{1}},
+		{"-tag:work -tag:personal", []int{4}},
+		{"NOT NOT tag:ops", []int{1}},
+		{"NOT (tag:work OR tag:personal)", []int{4}},
+		{`tag:work AND "plan review"`, []int{3}},
+
+		// Lower-case operators are words
+		{"much not", []int{2}},
+		{"milk or eggs", nil},
+		{"milk OR eggs", []int{2}},
+
+		// Dates are days in now's time zone; ages count back from now
+		{"created:2025-03-01", []int{1}},
+		{"created:=2025-03-10", []int{3}},
+		{"created:>2025-03-01", []int{3}},
+		{"created:>=2025-03-01", []int{1, 3}},
+		{"created:<2025-01-10", []int{4}},
+		{"created:<=2025-01-10", []int{2, 4}},
+		{"updated:<2d", []int{1, 3}},
+		{"updated:2d", []int{1, 3}},
+		{"updated:>30d", []int{2, 4}},
+		{"updated:<12h", []int{3}},
+		{"created:>1w updated:<1w", []int{1}},
+	}
+	for _, tt := range tests {
+		q, err := parseAt(tt.query, now)
+		if err != nil {
+			t.Errorf("parseAt(%q) failed: %v", tt.query, err)
+			continue
+		}
+		var got []int
+		for _, note := range q.Filter(queryNotes) {
+			got = append(got, note.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`"unclosed`, "unterminated quote"},
+		{`title:"weekly`, "unterminated quote"},
+		{"(tag:work", `missing ")"`},
+		{")", `unexpected ")"`},
+		{"tag:work OR", "ends unexpectedly"},
+		{"NOT", "ends unexpectedly"},
+		{"tag:", "tag: needs a value"},
+		{"created:yesterday", "expected a date"},
+		{"updated:<0d", "expected a date"},
+		{"created:2025-13-01", "expected a date"},
+	}
+	for _, tt := range tests {
+		_, err := parseAt(tt.query, now)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseAt(%q) error = %v, want one containing %q", tt.query, err, tt.want)
+		}
+	}
+}
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []token
+	}{
+		{"deploy plan", []token{
+			{kind: tokenTerm, value: "deploy"},
+			{kind: tokenTerm, value: "plan"},
+		}},
+		{`-tag:work OR "a b"`, []token{
+			{kind: tokenNot},
+			{kind: tokenTerm, field: "tag", value: "work"},
+			{kind: tokenOr},
+			{kind: tokenTerm, value: "a b", quoted: true},
+		}},
+		{`title:"weekly sync" NOT(c)`, []token{
+			{kind: tokenTerm, field: "title", value: "weekly sync", quoted: true},
+			{kind: tokenNot},
+			{kind: tokenOpen},
+			{kind: tokenTerm, value: "c"},
+			{kind: tokenClose},
+		}},
+		// Unknown fields, a lone dash and quoted operators are plain words
+		{`url:http://x - "OR" AND`, []token{
+			{kind: tokenTerm, value: "url:http://x"},
+			{kind: tokenTerm, value: "-"},
+			{kind: tokenTerm, value: "OR", quoted: true},
+			{kind: tokenAnd},
+		}},
+		{"Created:>=2025-01-01", []token{
+			{kind: tokenTerm, field: "created", value: ">=2025-01-01"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := lex(tt.query)
+		if err != nil {
+			t.Errorf("lex(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("lex(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
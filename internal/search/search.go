@@ -0,0 +1,86 @@
+// Package search finds notes matching a query. It is shared by the TUI and
+// the command line so both return the same results.
+package search
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+// Ranked searches notes for query, best matches first. A query that is
+// only words is looked up in ix; other queries are matched against every
+// note, with words matched the same way, and ranked by their words. Every
+// backend searches this way, so results do not depend on where notes are
+// stored. ix must hold notes, or be nil to index them on the spot.
+func Ranked(ix *Index, notes []store.Note, query string) ([]store.Note, error) {
+	q, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	if ix == nil {
+		ix = NewIndex(notes)
+	}
+	// Words without letters or digits match every note, as in Filter
+	words, plain := q.plainWords()
+	if !plain || len(Tokenize(strings.Join(words, " "))) == 0 {
+		return rank(q.Filter(notes), ix.Scores(strings.Join(q.rankWords(), " "))), nil
+	}
+	var ids []int
+	for _, hit := range ix.Search(strings.Join(words, " ")) {
+		ids = append(ids, hit.NoteID)
+	}
+	return pick(notes, ids), nil
+}
+
+// pick returns the notes with the given IDs, in the order of ids.
+func pick(notes []store.Note, ids []int) []store.Note {
+	byID := make(map[int]store.Note, len(notes))
+	for _, note := range notes {
+		byID[note.ID] = note
+	}
+	var results []store.Note
+	for _, id := range ids {
+		if note, ok := byID[id]; ok {
+			results = append(results, note)
+		}
+	}
+	return results
+}
+
+// rank orders notes by score, best first, keeping the order of notes with
+// the same score.
+func rank(notes []store.Note, scores map[int]float64) []store.Note {
+	slices.SortStableFunc(notes, func(a, b store.Note) int {
+		return cmp.Compare(scores[b.ID], scores[a.ID])
+	})
+	return notes
+}
+
+// containsWords reports whether texts have every word of value, matched
+// as Index.Search matches them: by stem, as prefixes and ignoring accents.
+// A value without letters or digits has no words, so it always matches.
+func containsWords(value string, texts ...string) bool {
+	var terms []string
+	for _, text := range texts {
+		terms = append(terms, Tokenize(text)...)
+	}
+	for _, word := range Tokenize(value) {
+		if !slices.ContainsFunc(terms, func(term string) bool { return strings.HasPrefix(term, word) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsTag checks if a slice of tags contains a specific query.
+func containsTag(tags []string, query string) bool {
+	for _, tag := range tags {
+		if strings.Contains(strings.ToLower(tag), query) {
+			return true
+		}
+	}
+	return false
+}
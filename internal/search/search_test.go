@@ -0,0 +1,39 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRankedMatchesWordsTheSameWay(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// Plain words are looked up in the index, other queries filter
+		// the notes; either way a word matches the same notes
+		{"ploy", nil},
+		{"ploy -tag:personal", nil},
+		{"dep", []int{1, 3}},
+		{"dep -tag:meeting", []int{1}},
+		{"plans", []int{1, 3}},
+		{"plans OR milk", []int{1, 2, 3}},
+		{"+++", []int{1, 2, 3, 4}},
+	}
+	ix := NewIndex(queryNotes)
+	for _, tt := range tests {
+		results, err := Ranked(ix, queryNotes, tt.query)
+		if err != nil {
+			t.Errorf("Ranked(%q) failed: %v", tt.query, err)
+			continue
+		}
+		var got []int
+		for _, note := range results {
+			got = append(got, note.ID)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Ranked(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits text into lower-cased, stemmed search terms. Words are
+// runs of letters, digits and combining marks in any script, and accents
+// are dropped from Latin letters so "cafe" finds "café". Han, Hiragana,
+// Katakana and Hangul are written without spaces, so each of their
+// characters is a term of its own.
+func Tokenize(text string) []string {
+	var terms []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			terms = append(terms, stem(string(word)))
+			word = word[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isIdeograph(r):
+			flush()
+			terms = append(terms, string(r))
+		case unicode.Is(unicode.Mn, r):
+			// Combining accents belong to the letter before them
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			word = append(word, foldAccent(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// accented maps accented Latin letters to the letter without the accent.
+var accented = map[rune]rune{}
+
+func init() {
+	for base, letters := range map[rune]string{
+		'a': "àáâãäåāăą", 'c': "çćĉċč", 'd': "ďđ", 'e': "èéêëēĕėęě",
+		'g': "ĝğġģ", 'h': "ĥħ", 'i': "ìíîïĩīĭįı", 'j': "ĵ", 'k': "ķ",
+		'l': "ĺļľŀł", 'n': "ñńņňŉ", 'o': "òóôõöøōŏő", 'r': "ŕŗř",
+		's': "śŝşš", 't': "ţťŧ", 'u': "ùúûüũūŭůűų", 'w': "ŵ", 'y': "ýÿŷ",
+		'z': "źżž",
+	} {
+		for _, r := range letters {
+			accented[r] = base
+		}
+	}
+}
+
+// foldAccent returns r without its accent if it is an accented Latin
+// letter.
+func foldAccent(r rune) rune {
+	if base, ok := accented[r]; ok {
+		return base
+	}
+	return r
+}
+
+// isIdeograph reports whether r belongs to a script written without
+// spaces between words.
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// stem reduces an English word to its stem by removing common
+// inflections, so "notes", "noted" and "noting" all become "note". It is a
+// light version of Porter's first step: words in other languages and
+// words too short to tell are left alone.
+func stem(word string) string {
+	if len(word) < 4 || !isASCII(word) {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies"):
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+	case strings.HasSuffix(word, "s"):
+		word = word[:len(word)-1]
+	}
+	for _, suffix := range []string{"ing", "ed"} {
+		base, ok := strings.CutSuffix(word, suffix)
+		if !ok || len(base) < 3 || !hasVowel(base) || strings.HasSuffix(word, "eed") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(base, "at"), strings.HasSuffix(base, "bl"), strings.HasSuffix(base, "iz"):
+			// "related" -> "relate", "enabled" -> "enable"
+			base += "e"
+		case len(base) > 3 && doubleConsonant(base):
+			// "planned" -> "plan"
+			base = base[:len(base)-1]
+		case len(base) == 3 && isConsonant(base, 0) && !isConsonant(base, 1) && isConsonant(base, 2) && !strings.ContainsAny(base[2:], "wxy"):
+			// "noted" -> "note"
+			base += "e"
+		}
+		return base
+	}
+	return word
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= unicode.MaxASCII || !unicode.IsLetter(rune(s[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func isConsonant(word string, i int) bool {
+	switch word[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(word, i-1)
+	}
+	return true
+}
+
+func hasVowel(word string) bool {
+	for i := range len(word) {
+		if !isConsonant(word, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleConsonant reports whether word ends in a doubled consonant that
+// inflection adds, as in "planned"; l, s and z are usually part of the
+// stem, as in "filled".
+func doubleConsonant(word string) bool {
+	n := len(word)
+	return n >= 2 && word[n-1] == word[n-2] && isConsonant(word, n-1) && !strings.ContainsAny(word[n-1:], "lsz")
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Hello, World!", []string{"hello", "world"}},
+		{"Notes noted noting", []string{"note", "note", "note"}},
+		{"go1.24 v2", []string{"go1", "24", "v2"}},
+
+		// Accents are dropped, whether precomposed or combining
+		{"café Crème brûlée", []string{"cafe", "creme", "brulee"}},
+		{"école naïve", []string{"ecole", "naive"}},
+		{"Straße", []string{"straße"}},
+
+		// Other scripts are kept whole and not stemmed
+		{"Привет мир", []string{"привет", "мир"}},
+		{"日本語テキスト", []string{"日", "本", "語", "テ", "キ", "ス", "ト"}},
+		{"한국 notes", []string{"한", "국", "note"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := []struct{ word, want string }{
+		{"notes", "note"},
+		{"classes", "class"},
+		{"ponies", "pony"},
+		{"tries", "try"},
+		{"status", "status"},
+		{"analysis", "analysis"},
+		{"planned", "plan"},
+		{"planning", "plan"},
+		{"running", "run"},
+		{"fixed", "fix"},
+		{"related", "relate"},
+		{"enabled", "enable"},
+		{"noted", "note"},
+		{"hoping", "hope"},
+		{"filled", "fill"},
+		{"added", "add"},
+		{"speed", "speed"},
+		{"agreed", "agreed"},
+		{"sing", "sing"},
+		{"bus", "bus"},
+		{"résumés", "résumés"},
+	}
+	for _, tt := range tests {
+		if got := stem(tt.word); got != tt.want {
+			t.Errorf("stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// backupSuffix is appended to a data file's name to form its backup copy.
+const backupSuffix = ".bak"
+
+// writeFileAtomic replaces path with content so that a crash at any point
+// leaves either the old or the new file in place, never a partial one. The
+// previous contents are kept next to it with a ".bak" suffix.
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
+	if previous, err := os.ReadFile(path); err == nil {
+		if err := replaceFile(path+backupSuffix, previous, perm); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return replaceFile(path, content, perm)
+}
+
+// replaceFile writes content to a temporary file in the same directory,
+// flushes it to disk and renames it over path.
+func replaceFile(path string, content []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once the rename has succeeded
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes a directory entry so a completed rename survives a crash.
+// Not every platform supports this, so failures are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FolderSeparator separates the levels of a nested folder path, as in
+// "Work/ProjectX/Design".
+const FolderSeparator = "/"
+
+// CleanFolderPath trims the spaces around every level of a folder path
+// and rejects paths with an empty level. Levels are also directory names
+// in the Markdown backend, so they cannot start with a dot, which rules
+// out "." and "..", or contain a backslash, the Windows path separator.
+func CleanFolderPath(path string) (string, error) {
+	parts := strings.Split(path, FolderSeparator)
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+		switch {
+		case parts[i] == "":
+			return "", fmt.Errorf("folder path %q has an empty level", path)
+		case strings.HasPrefix(parts[i], "."):
+			return "", fmt.Errorf("folder name %q cannot start with a dot", parts[i])
+		case strings.Contains(parts[i], `\`):
+			return "", fmt.Errorf("folder name %q cannot contain a backslash", parts[i])
+		}
+	}
+	return strings.Join(parts, FolderSeparator), nil
+}
+
+// FolderParent returns the path of the folder containing path, or "" for
+// a top-level folder.
+func FolderParent(path string) string {
+	i := strings.LastIndex(path, FolderSeparator)
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+// FolderName returns the last level of a folder path.
+func FolderName(path string) string {
+	return path[strings.LastIndex(path, FolderSeparator)+1:]
+}
+
+// FolderDepth returns how deeply path is nested; top-level folders are at
+// depth 0.
+func FolderDepth(path string) int {
+	return strings.Count(path, FolderSeparator)
+}
+
+// InFolder reports whether path is folder itself or one of its
+// descendants.
+func InFolder(path, folder string) bool {
+	return path == folder || strings.HasPrefix(path, folder+FolderSeparator)
+}
+
+// folderAncestors returns the paths of the folders containing path,
+// outermost first.
+func folderAncestors(path string) []string {
+	var ancestors []string
+	for parent := FolderParent(path); parent != ""; parent = FolderParent(parent) {
+		ancestors = append([]string{parent}, ancestors...)
+	}
+	return ancestors
+}
+
+// AddFolder adds a folder, and any of its parents that do not exist yet,
+// to the folder list.
+func (d *AppData) AddFolder(path string) error {
+	path, err := CleanFolderPath(path)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(d.Folders, path) {
+		return fmt.Errorf("folder %q already exists", path)
+	}
+	d.ensureFolder(path)
+	return nil
+}
+
+// ensureFolder adds path and its parents to the folder list where they
+// are missing, parents first.
+func (d *AppData) ensureFolder(path string) {
+	for _, folder := range append(folderAncestors(path), path) {
+		if !slices.Contains(d.Folders, folder) {
+			d.Folders = append(d.Folders, folder)
+		}
+	}
+}
+
+// FolderTree returns the folders in tree order: every folder is followed
+// by its subfolders, and siblings keep the order of the folder list.
+func (d *AppData) FolderTree() []string {
+	children := map[string][]string{}
+	for _, folder := range d.Folders {
+		parent := FolderParent(folder)
+		if parent != "" && !slices.Contains(d.Folders, parent) {
+			// Shown at the top level until Repair adds the parent
+			parent = ""
+		}
+		children[parent] = append(children[parent], folder)
+	}
+	var tree []string
+	var walk func(parent string)
+	walk = func(parent string) {
+		for _, folder := range children[parent] {
+			tree = append(tree, folder)
+			walk(folder)
+		}
+	}
+	walk("")
+	return tree
+}
+
+// MoveFolder moves a folder with all its subfolders and notes into
+// parent, or to the top level if parent is "". It returns how many notes
+// were refiled.
+func (d *AppData) MoveFolder(path, parent string) (int, error) {
+	to := FolderName(path)
+	if parent != "" {
+		if !slices.Contains(d.Folders, parent) {
+			return 0, fmt.Errorf("folder %q does not exist", parent)
+		}
+		to = parent + FolderSeparator + to
+	}
+	return d.RenameFolder(path, to)
+}
+
+// relocate moves the folder from and everything below it to to, merging
+// with folders that already exist there, and keeps the default folder
+// pointing at the same place. It returns how many notes were refiled.
+func (d *AppData) relocate(from, to string) int {
+	var folders []string
+	for _, folder := range d.Folders {
+		if InFolder(folder, from) {
+			folder = to + strings.TrimPrefix(folder, from)
+		}
+		if !slices.Contains(folders, folder) {
+			folders = append(folders, folder)
+		}
+	}
+	d.Folders = folders
+	d.ensureFolder(to)
+	if InFolder(d.DefaultFolder, from) {
+		d.DefaultFolder = to + strings.TrimPrefix(d.DefaultFolder, from)
+	}
+	return d.refile(from, to)
+}
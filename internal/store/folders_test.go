@@ -0,0 +1,36 @@
+package store
+
+import "testing"
+
+func TestCleanFolderPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "Work", want: "Work"},
+		{path: " Work / Project X ", want: "Work/Project X"},
+		{path: "Work/v1.2", want: "Work/v1.2"},
+		{path: "Work//Design", wantErr: true},
+		{path: "Work/", wantErr: true},
+		{path: " ", wantErr: true},
+		{path: ".", wantErr: true},
+		{path: "..", wantErr: true},
+		{path: "Work/../..", wantErr: true},
+		{path: ".hidden", wantErr: true},
+		{path: "Work/ .git", wantErr: true},
+		{path: `Work\Design`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := CleanFolderPath(tt.path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("CleanFolderPath(%q) = %q, want an error", tt.path, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("CleanFolderPath(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
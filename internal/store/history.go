@@ -0,0 +1,140 @@
+package store
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// Revision is a saved state of a note. A note's history is the list of its
+// revisions, oldest first; restoring an old revision adds a new one, so
+// history is never rewritten.
+type Revision struct {
+	NoteID int `json:"note_id"`
+	// SavedAt is when the note was saved in this state. Together with
+	// NoteID it identifies the revision, so it is unique within a note's
+	// history.
+	SavedAt time.Time `json:"saved_at"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Tags    []string  `json:"tags"`
+	// Folder is empty in revisions recorded before folders were kept.
+	Folder string `json:"folder,omitempty"`
+}
+
+// History returns the revisions of the note with the given ID, oldest
+// first.
+func (d *AppData) History(id int) []Revision {
+	var history []Revision
+	for _, rev := range d.Revisions {
+		if rev.NoteID == id {
+			history = append(history, rev)
+		}
+	}
+	return history
+}
+
+// AddRevision records the state of note as its newest revision, unless
+// that is what the newest revision already holds. It reports whether a
+// revision was added. The revision is stamped with the note's UpdatedAt,
+// or just after the newest revision if that is not later, so that every
+// revision of a note has a time of its own.
+func (d *AppData) AddRevision(note Note) bool {
+	history := d.History(note.ID)
+	savedAt := note.UpdatedAt
+	if len(history) > 0 {
+		last := history[len(history)-1]
+		if last.Title == note.Title && last.Content == note.Content && slices.Equal(last.Tags, note.Tags) && last.Folder == note.Folder {
+			return false
+		}
+		for _, rev := range history {
+			if !savedAt.After(rev.SavedAt) {
+				savedAt = rev.SavedAt.Add(time.Nanosecond)
+			}
+		}
+	}
+	d.Revisions = append(d.Revisions, Revision{
+		NoteID:  note.ID,
+		SavedAt: savedAt,
+		Title:   note.Title,
+		Content: note.Content,
+		Tags:    slices.Clone(note.Tags),
+		Folder:  note.Folder,
+	})
+	return true
+}
+
+// RecordEdit replaces the stored note with the edited one and records the
+// edit in its history. The stored state is recorded first if it is not in
+// the history yet, so notes created before history was kept, or changed
+// outside the TUI, keep their previous text.
+func (d *AppData) RecordEdit(edited Note) {
+	if previous, ok := d.Note(edited.ID); ok {
+		d.AddRevision(previous)
+	}
+	d.PutNote(edited)
+	d.AddRevision(edited)
+}
+
+// RestoreRevision makes rev the current state of its note and records it
+// as a new revision. The note moves back to the revision's folder only if
+// that folder still exists. It returns false if the note no longer exists.
+func (d *AppData) RestoreRevision(rev Revision) (Note, bool) {
+	note, ok := d.Note(rev.NoteID)
+	if !ok {
+		return Note{}, false
+	}
+	note.Title = rev.Title
+	note.Content = rev.Content
+	note.Tags = slices.Clone(rev.Tags)
+	if slices.Contains(d.Folders, rev.Folder) {
+		note.Folder = rev.Folder
+	}
+	note.UpdatedAt = time.Now()
+	d.RecordEdit(note)
+	return note, true
+}
+
+// revisionKey identifies a revision across copies of a notebook.
+type revisionKey struct {
+	noteID  int
+	savedAt int64
+}
+
+func (r Revision) key() revisionKey {
+	return revisionKey{r.NoteID, r.SavedAt.UnixNano()}
+}
+
+// mergeRevisions combines the histories of two copies of a notebook that
+// both started from base: revisions added on either side are kept and
+// revisions dropped on either side are dropped.
+func mergeRevisions(base, mine, theirs []Revision) []Revision {
+	inBase := map[revisionKey]bool{}
+	for _, rev := range base {
+		inBase[rev.key()] = true
+	}
+	inMine := map[revisionKey]bool{}
+	for _, rev := range mine {
+		inMine[rev.key()] = true
+	}
+
+	inTheirs := map[revisionKey]bool{}
+	var merged []Revision
+	for _, rev := range theirs {
+		inTheirs[rev.key()] = true
+		if inBase[rev.key()] && !inMine[rev.key()] {
+			continue
+		}
+		merged = append(merged, rev)
+	}
+	for _, rev := range mine {
+		if inTheirs[rev.key()] || inBase[rev.key()] {
+			continue
+		}
+		merged = append(merged, rev)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].SavedAt.Before(merged[j].SavedAt)
+	})
+	return merged
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddRevisionStampsAreUnique(t *testing.T) {
+	data := DefaultData()
+	note := data.NewNote("Plan", data.DefaultFolder)
+	data.PutNote(note)
+
+	// Edits that do not move UpdatedAt on, as folder moves once did
+	for _, content := range []string{"one", "two", "three"} {
+		note.Content = content
+		if !data.AddRevision(note) {
+			t.Fatalf("revision %q not added", content)
+		}
+	}
+	history := data.History(note.ID)
+	for i := 1; i < len(history); i++ {
+		if !history[i].SavedAt.After(history[i-1].SavedAt) {
+			t.Errorf("revision %d saved at %v, not after revision %d at %v", i, history[i].SavedAt, i-1, history[i-1].SavedAt)
+		}
+	}
+}
+
+func TestFolderMovesAreRecorded(t *testing.T) {
+	data := DefaultData()
+	data.AddFolder("Archive")
+	data.AddFolder("Old")
+	note := data.NewNote("Plan", "Old")
+	data.RecordEdit(note)
+
+	if _, err := data.DeleteFolder("Old", "Archive"); err != nil {
+		t.Fatal(err)
+	}
+	history := data.History(note.ID)
+	if len(history) != 2 || history[0].Folder != "Old" || history[1].Folder != "Archive" {
+		t.Fatalf("history after the move = %+v, want revisions in Old then Archive", history)
+	}
+
+	// Old is gone, so restoring the first revision leaves the note in
+	// Archive; the other way round it moves back
+	if restored, _ := data.RestoreRevision(history[0]); restored.Folder != "Archive" {
+		t.Errorf("restored into %q, want Archive as Old no longer exists", restored.Folder)
+	}
+	data.AddFolder("Old")
+	if restored, _ := data.RestoreRevision(history[0]); restored.Folder != "Old" {
+		t.Errorf("restored into %q, want Old", restored.Folder)
+	}
+}
+
+func TestRepairSeparatesRevisionsSavedTogether(t *testing.T) {
+	data := DefaultData()
+	note := data.NewNote("Plan", data.DefaultFolder)
+	data.PutNote(note)
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	data.Revisions = []Revision{
+		{NoteID: note.ID, SavedAt: at, Content: "one"},
+		{NoteID: note.ID, SavedAt: at, Content: "two"},
+		{NoteID: note.ID, SavedAt: at.Add(time.Nanosecond), Content: "three"},
+	}
+
+	data.Repair("")
+	seen := map[revisionKey]bool{}
+	for _, rev := range data.Revisions {
+		if seen[rev.key()] {
+			t.Errorf("revision %q still shares its time %v", rev.Content, rev.SavedAt)
+		}
+		seen[rev.key()] = true
+	}
+}
+
+func TestSQLiteKeepsRevisionsWithTheSameUpdateTime(t *testing.T) {
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "quicknotes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	data, err := st.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := data.NewNote("Plan", data.DefaultFolder)
+	data.PutNote(note)
+	for _, content := range []string{"one", "two", "three"} {
+		note.Content = content
+		data.AddRevision(note)
+	}
+	if err := st.Save(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropping one revision must leave the others alone
+	data.Revisions = append(data.Revisions[:1], data.Revisions[2:]...)
+	if err := st.Save(data); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewSQLiteStore(st.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	saved, err := reopened.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, rev := range saved.History(note.ID) {
+		contents = append(contents, rev.Content)
+		if rev.Folder != note.Folder {
+			t.Errorf("revision %q saved in folder %q, want %q", rev.Content, rev.Folder, note.Folder)
+		}
+	}
+	if len(contents) != 2 || contents[0] != "one" || contents[1] != "three" {
+		t.Errorf("history after dropping a revision = %q, want [one three]", contents)
+	}
+}
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DeleteFolder removes a folder and its subfolders from the folder list
+// and moves every note filed in them, trashed ones included, to moveTo.
+// It returns how many notes were moved. The default folder, and the
+// folders containing it, cannot be deleted.
+func (d *AppData) DeleteFolder(name, moveTo string) (int, error) {
+	if InFolder(d.DefaultFolder, name) {
+		return 0, fmt.Errorf("%q contains the default folder; make another folder the default first", name)
+	}
+	if InFolder(moveTo, name) {
+		return 0, fmt.Errorf("cannot move the notes of %q into itself", name)
+	}
+	moved := 0
+	for _, note := range d.Notes {
+		if InFolder(note.Folder, name) {
+			note.Folder = moveTo
+			note.UpdatedAt = time.Now()
+			d.RecordEdit(note)
+			moved++
+		}
+	}
+	d.Folders = slices.DeleteFunc(d.Folders, func(folder string) bool { return InFolder(folder, name) })
+	if moved > 0 {
+		d.ensureFolder(moveTo)
+	}
+	return moved, nil
+}
+
+// TrashFolder moves the notes of a folder and its subfolders to the trash
+// and removes the folders. The notes are refiled in the default folder,
+// where they reappear if they are restored. It returns how many notes were
+// trashed.
+func (d *AppData) TrashFolder(name string) (int, error) {
+	if InFolder(d.DefaultFolder, name) {
+		return 0, fmt.Errorf("%q contains the default folder; make another folder the default first", name)
+	}
+	trashed := 0
+	for _, note := range d.Notes {
+		if InFolder(note.Folder, name) && d.TrashNote(note.ID) {
+			trashed++
+		}
+	}
+	_, err := d.DeleteFolder(name, d.DefaultFolder)
+	return trashed, err
+}
+
+// SetDefaultFolder makes an existing folder the default folder.
+func (d *AppData) SetDefaultFolder(name string) error {
+	if !slices.Contains(d.Folders, name) {
+		return fmt.Errorf("folder %q does not exist", name)
+	}
+	d.DefaultFolder = name
+	return nil
+}
+
+// DeleteTag removes a tag from the tag list and from every note carrying
+// it, and returns how many notes it was removed from.
+func (d *AppData) DeleteTag(tag string) int {
+	stripped := 0
+	for _, note := range d.Notes {
+		if slices.Contains(note.Tags, tag) {
+			note.Tags = slices.DeleteFunc(slices.Clone(note.Tags), func(t string) bool { return t == tag })
+			note.UpdatedAt = time.Now()
+			d.RecordEdit(note)
+			stripped++
+		}
+	}
+	d.Tags = slices.DeleteFunc(d.Tags, func(t string) bool { return t == tag })
+	return stripped
+}
+
+// Repair fixes references that do not resolve, as left behind by versions
+// that deleted folders and tags without updating their notes, or by edits
+// made outside QuickNotes. Nothing is deleted: folders and tags used by
+// notes, and the parents of nested folders, are added back to their lists,
+// notes without a folder are filed in the default folder, duplicate list
+// entries and the history of notes that no longer exist are dropped, and
+// revisions of a note recorded with the same time are moved apart so each
+// can be told from the others. A notebook without a default folder, such
+// as one written before they existed, gets fallback. It returns a
+// description of each fix.
+func (d *AppData) Repair(fallback string) []string {
+	var fixes []string
+
+	if d.DefaultFolder == "" {
+		d.DefaultFolder = fallback
+		if d.DefaultFolder == "" && len(d.Folders) > 0 {
+			d.DefaultFolder = d.Folders[0]
+		}
+		if d.DefaultFolder == "" {
+			d.DefaultFolder = DefaultData().DefaultFolder
+		}
+	}
+
+	if folders := dedupe(d.Folders); len(folders) != len(d.Folders) {
+		fixes = append(fixes, "removed duplicate folders")
+		d.Folders = folders
+	}
+	if tags := dedupe(d.Tags); len(tags) != len(d.Tags) {
+		fixes = append(fixes, "removed duplicate tags")
+		d.Tags = tags
+	}
+
+	if !slices.Contains(d.Folders, d.DefaultFolder) {
+		d.Folders = append([]string{d.DefaultFolder}, d.Folders...)
+		fixes = append(fixes, fmt.Sprintf("restored missing folder %s", d.DefaultFolder))
+	}
+
+	for i := range d.Notes {
+		note := &d.Notes[i]
+		if note.Folder == "" {
+			note.Folder = d.DefaultFolder
+			fixes = append(fixes, fmt.Sprintf("filed note %d in %s", note.ID, d.DefaultFolder))
+		}
+		if !slices.Contains(d.Folders, note.Folder) {
+			d.Folders = append(d.Folders, note.Folder)
+			fixes = append(fixes, fmt.Sprintf("restored missing folder %s", note.Folder))
+		}
+		for _, tag := range note.Tags {
+			if !slices.Contains(d.Tags, tag) {
+				d.Tags = append(d.Tags, tag)
+				fixes = append(fixes, fmt.Sprintf("restored missing tag %s", tag))
+			}
+		}
+	}
+
+	for _, folder := range slices.Clone(d.Folders) {
+		for _, parent := range folderAncestors(folder) {
+			if !slices.Contains(d.Folders, parent) {
+				d.Folders = append(d.Folders, parent)
+				fixes = append(fixes, fmt.Sprintf("restored missing folder %s", parent))
+			}
+		}
+	}
+
+	exists := make(map[int]bool, len(d.Notes))
+	for _, note := range d.Notes {
+		exists[note.ID] = true
+	}
+	orphaned := 0
+	d.Revisions = slices.DeleteFunc(d.Revisions, func(rev Revision) bool {
+		if !exists[rev.NoteID] {
+			orphaned++
+		}
+		return !exists[rev.NoteID]
+	})
+	if orphaned > 0 {
+		fixes = append(fixes, fmt.Sprintf("dropped %d revisions of deleted notes", orphaned))
+	}
+
+	restamped := 0
+	seen := make(map[revisionKey]bool, len(d.Revisions))
+	for i := range d.Revisions {
+		if seen[d.Revisions[i].key()] {
+			restamped++
+		}
+		for seen[d.Revisions[i].key()] {
+			d.Revisions[i].SavedAt = d.Revisions[i].SavedAt.Add(time.Nanosecond)
+		}
+		seen[d.Revisions[i].key()] = true
+	}
+	if restamped > 0 {
+		fixes = append(fixes, fmt.Sprintf("separated %d revisions saved at the same time", restamped))
+	}
+	return fixes
+}
+
+// dedupe returns values without repeated entries, keeping the first.
+func dedupe(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if !slices.Contains(result, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,264 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// JSONStore keeps the whole notebook in a single JSON file. Reads and
+// writes take an advisory lock on a sibling ".lock" file so several
+// QuickNotes processes can share it; a save that finds the file changed
+// since it was read merges the other process's changes first.
+type JSONStore struct {
+	// Seed is the notebook written on first run; DefaultData() if nil.
+	Seed *AppData
+
+	path string
+	data *AppData
+
+	// The file as this process last read or wrote it.
+	base    []byte
+	modTime time.Time
+	size    int64
+}
+
+// NewJSONStore returns a store backed by the JSON file at path.
+func NewJSONStore(path string) *JSONStore {
+	return &JSONStore{path: path}
+}
+
+// Path returns the data file location.
+func (s *JSONStore) Path() string {
+	return s.path
+}
+
+// Load reads the data file, creating it with the defaults on first run.
+func (s *JSONStore) Load() (*AppData, error) {
+	lock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlockFile(lock)
+
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		// A notebook that was set aside as unreadable must be restored or
+		// explicitly started over, not silently replaced by a fresh one.
+		if corrupt := s.quarantined(); corrupt != "" {
+			return nil, &CorruptError{Path: s.path, Err: fmt.Errorf("the file is missing and an unreadable copy was set aside as %s", corrupt)}
+		}
+		data := s.seed()
+		if err := s.write(data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	upgraded, version, err := migrate(s.path, content)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.decode(upgraded)
+	if err != nil {
+		return nil, err
+	}
+	if version < SchemaVersion {
+		// Keep the file as the older build wrote it before upgrading.
+		backup := fmt.Sprintf("%s%s-v%d", s.path, backupSuffix, version)
+		if err := replaceFile(backup, content, 0644); err != nil {
+			return nil, err
+		}
+		if err := s.write(data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	s.remember(content)
+	s.data = data
+	return data, nil
+}
+
+// Save atomically replaces the data file, keeping the previous version as
+// a backup. If another process wrote the file since it was last read, its
+// changes are merged into data first; a *ConflictError is returned after
+// saving when some of them clashed with ours.
+func (s *JSONStore) Save(data *AppData) error {
+	lock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlockFile(lock)
+
+	var conflicts []Conflict
+	if theirContent, changed, err := s.changedOnDisk(); err != nil {
+		return err
+	} else if changed {
+		theirContent, _, err = migrate(s.path, theirContent)
+		if err != nil {
+			return err
+		}
+		theirs, err := s.decode(theirContent)
+		if err != nil {
+			return err
+		}
+		var merged *AppData
+		if s.base == nil {
+			// Nothing was read since a restore or quarantine, so there is
+			// no common ancestor to merge from
+			merged, conflicts = mergeWithoutBase(data, theirs)
+		} else {
+			base := &AppData{}
+			if err := json.Unmarshal(s.base, base); err != nil {
+				return err
+			}
+			merged, conflicts = merge(base, data, theirs)
+		}
+		*data = *merged
+	}
+
+	if err := s.write(data); err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// seed returns the notebook to write when there is none yet.
+func (s *JSONStore) seed() *AppData {
+	if s.Seed != nil {
+		return s.Seed
+	}
+	return DefaultData()
+}
+
+// lock takes the cross-process lock guarding the data file.
+func (s *JSONStore) lock() (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, err
+	}
+	return lockFile(s.path + ".lock")
+}
+
+// decode parses a data file already at the current schema version. Files
+// written before default folders existed decode without one, so that
+// AppData.Repair can pick the configured default.
+func (s *JSONStore) decode(content []byte) (*AppData, error) {
+	data := DefaultData()
+	data.DefaultFolder = ""
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, &CorruptError{Path: s.path, Err: err}
+	}
+	return data, nil
+}
+
+// write replaces the data file; the caller must hold the lock.
+func (s *JSONStore) write(data *AppData) error {
+	data.SchemaVersion = SchemaVersion
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, content, 0644); err != nil {
+		return err
+	}
+	s.remember(content)
+	s.data = data
+	return nil
+}
+
+// remember records the file as written or read by this process.
+func (s *JSONStore) remember(content []byte) {
+	s.base = content
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+}
+
+// changedOnDisk reports whether another process has written the data file
+// since this one last read or wrote it, returning the new contents if so.
+func (s *JSONStore) changedOnDisk() ([]byte, bool, error) {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if s.base != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil, false, nil
+	}
+
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, false, err
+	}
+	if s.base != nil && bytes.Equal(content, s.base) {
+		s.modTime, s.size = info.ModTime(), info.Size()
+		return nil, false, nil
+	}
+	return content, true, nil
+}
+
+// loaded returns the cached notebook, reading it from disk if needed.
+func (s *JSONStore) loaded() (*AppData, error) {
+	if s.data != nil {
+		return s.data, nil
+	}
+	return s.Load()
+}
+
+func (s *JSONStore) GetNote(id int) (Note, error) {
+	data, err := s.loaded()
+	if err != nil {
+		return Note{}, err
+	}
+	if note, ok := data.Note(id); ok {
+		return note, nil
+	}
+	return Note{}, ErrNotFound
+}
+
+func (s *JSONStore) PutNote(note Note) error {
+	data, err := s.loaded()
+	if err != nil {
+		return err
+	}
+	data.PutNote(note)
+	return s.Save(data)
+}
+
+func (s *JSONStore) DeleteNote(id int) error {
+	data, err := s.loaded()
+	if err != nil {
+		return err
+	}
+	if !data.DeleteNote(id) {
+		return ErrNotFound
+	}
+	return s.Save(data)
+}
+
+func (s *JSONStore) ListFolders() ([]string, error) {
+	data, err := s.loaded()
+	if err != nil {
+		return nil, err
+	}
+	return data.Folders, nil
+}
+
+func (s *JSONStore) ListTags() ([]string, error) {
+	data, err := s.loaded()
+	if err != nil {
+		return nil, err
+	}
+	return data.Tags, nil
+}
@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+
+package store
+
+import "os"
+
+// lockFile opens path without locking it; this platform has no advisory
+// locks, so concurrent writers are only caught by the modification check.
+func lockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+}
+
+// unlockFile releases a file opened with lockFile.
+func unlockFile(f *os.File) error {
+	return f.Close()
+}
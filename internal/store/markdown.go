@@ -0,0 +1,584 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// markdownMetaFile holds everything about a markdown notebook that does
+// not live in the notes themselves.
+const markdownMetaFile = ".quicknotes.json"
+
+// MarkdownStore keeps every note as a Markdown file at
+// <dir>/<folder>/<slug>.md, with its ID, tags and timestamps in YAML front
+// matter. Folders are real directories, so notes can be grepped, versioned
+// and edited with other tools; files added or changed outside QuickNotes
+// are picked up on the next load.
+type MarkdownStore struct {
+	// Seed is the notebook created on first run; DefaultData() if nil.
+	Seed *AppData
+
+	dir  string
+	data *AppData
+
+	// The file each note was last read from or written to, and the folders
+	// as of the last load or save.
+	files   map[int]markdownFile
+	folders []string
+
+	// Problems with note files found by the last load
+	warnings []string
+}
+
+// markdownFile is a note file as this process last saw it.
+type markdownFile struct {
+	path    string
+	title   string
+	content []byte
+}
+
+// markdownMeta is the layout of the .quicknotes.json file.
+type markdownMeta struct {
+	SchemaVersion int        `json:"schema_version"`
+	Folders       []string   `json:"folders"`
+	Tags          []string   `json:"tags"`
+	Templates     []Template `json:"templates"`
+	Revisions     []Revision `json:"revisions,omitempty"`
+	NextID        int        `json:"next_id"`
+	DefaultFolder string     `json:"default_folder,omitempty"`
+}
+
+// frontMatter is the YAML header of a note file.
+type frontMatter struct {
+	ID      int        `yaml:"id"`
+	Title   string     `yaml:"title,omitempty"`
+	Tags    []string   `yaml:"tags,flow"`
+	Created time.Time  `yaml:"created"`
+	Updated time.Time  `yaml:"updated"`
+	Deleted *time.Time `yaml:"deleted,omitempty"`
+}
+
+// NewMarkdownStore returns a store keeping its notes under dir.
+func NewMarkdownStore(dir string) *MarkdownStore {
+	return &MarkdownStore{dir: dir}
+}
+
+// Load scans the notebook directory. Notes without an ID, such as files
+// created by other tools, are given one and rewritten with front matter.
+// Files whose front matter cannot be read are left alone and reported by
+// Warnings instead of failing the whole load.
+func (s *MarkdownStore) Load() (*AppData, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+	lock, err := lockFile(filepath.Join(s.dir, ".quicknotes.lock"))
+	if err != nil {
+		return nil, err
+	}
+	defer unlockFile(lock)
+
+	data, err := s.readMeta()
+	if err != nil {
+		return nil, err
+	}
+	s.files = map[int]markdownFile{}
+	s.warnings = nil
+
+	var unnumbered []Note
+	var unnumberedFiles []markdownFile
+	err = filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == s.dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if folder := filepath.ToSlash(rel); !slices.Contains(data.Folders, folder) {
+				data.Folders = append(data.Folders, folder)
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".md" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		note, err := s.readNote(path, content, info.ModTime())
+		if err != nil {
+			s.warnings = append(s.warnings, fmt.Sprintf("skipped %s: %v", filepath.ToSlash(rel), err))
+			return nil
+		}
+
+		file := markdownFile{path: path, title: note.Title, content: content}
+		if _, taken := s.files[note.ID]; note.ID <= 0 || taken {
+			unnumbered = append(unnumbered, note)
+			unnumberedFiles = append(unnumberedFiles, file)
+			return nil
+		}
+		data.Notes = append(data.Notes, note)
+		s.files[note.ID] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, note := range data.Notes {
+		data.NextID = max(data.NextID, note.ID+1)
+	}
+	for i, note := range unnumbered {
+		note.ID = data.NextID
+		data.NextID++
+		rendered, err := renderMarkdownNote(note)
+		if err != nil {
+			return nil, err
+		}
+		file := unnumberedFiles[i]
+		if err := replaceFile(file.path, rendered, 0644); err != nil {
+			return nil, err
+		}
+		file.content = rendered
+		data.Notes = append(data.Notes, note)
+		s.files[note.ID] = file
+	}
+	sort.Slice(data.Notes, func(i, j int) bool {
+		return data.Notes[i].ID < data.Notes[j].ID
+	})
+
+	if len(unnumbered) > 0 {
+		if err := s.writeMeta(data); err != nil {
+			return nil, err
+		}
+	}
+	s.data = data
+	s.folders = slices.Clone(data.Folders)
+	return data, nil
+}
+
+// Warnings describes the note files the last Load skipped.
+func (s *MarkdownStore) Warnings() []string {
+	return s.warnings
+}
+
+// Save writes the notes that changed since they were last read or
+// written, moves files whose title or folder changed and removes the files
+// of deleted notes. A file another program changed in the meantime is not
+// lost: if the note was edited here too, the other version is added as a
+// conflicted copy, and if it was deleted here, it is kept. A
+// *ConflictError is returned after saving when that happened.
+func (s *MarkdownStore) Save(data *AppData) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	lock, err := lockFile(filepath.Join(s.dir, ".quicknotes.lock"))
+	if err != nil {
+		return err
+	}
+	defer unlockFile(lock)
+	if s.files == nil {
+		s.files = map[int]markdownFile{}
+	}
+
+	for _, folder := range data.Folders {
+		if err := os.MkdirAll(s.folderDir(folder), 0755); err != nil {
+			return err
+		}
+	}
+
+	claimed := map[string]bool{}
+	for _, note := range data.Notes {
+		if file, ok := s.files[note.ID]; ok {
+			claimed[file.path] = true
+		}
+	}
+	var conflicts []Conflict
+	var copies []Note
+	for _, note := range data.Notes {
+		theirs, err := s.writeNote(note, claimed)
+		if err != nil {
+			return err
+		}
+		if theirs != nil {
+			theirCopy := s.noteFromConflict(s.files[note.ID].path, theirs)
+			theirCopy.ID = data.NextID + len(copies)
+			theirCopy.Title += " (conflicted copy)"
+			theirCopy.Folder = note.Folder
+			copies = append(copies, theirCopy)
+			conflicts = append(conflicts, Conflict{NoteID: note.ID, Title: note.Title, Reason: "edited in both places, their version saved as a copy"})
+		}
+	}
+	for _, note := range copies {
+		data.PutNote(note)
+		if _, err := s.writeNote(note, claimed); err != nil {
+			return err
+		}
+	}
+
+	for id, file := range s.files {
+		if _, ok := data.Note(id); ok {
+			continue
+		}
+		if theirs, changed := s.changedOnDisk(file); changed {
+			note := s.noteFromConflict(file.path, theirs)
+			note.ID = id
+			data.PutNote(note)
+			data.ensureFolder(note.Folder)
+			s.files[id] = markdownFile{path: file.path, title: note.Title, content: theirs}
+			conflicts = append(conflicts, Conflict{NoteID: id, Title: note.Title, Reason: "deleted here but edited elsewhere, kept their edits"})
+			continue
+		}
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(s.files, id)
+	}
+	// Directories of deleted folders go too, as long as they are empty.
+	// Subfolders are removed before the folders containing them.
+	deleted := slices.DeleteFunc(slices.Clone(s.folders), func(folder string) bool {
+		return slices.Contains(data.Folders, folder)
+	})
+	slices.SortFunc(deleted, func(a, b string) int { return FolderDepth(b) - FolderDepth(a) })
+	for _, folder := range deleted {
+		os.Remove(s.folderDir(folder))
+	}
+
+	if err := s.writeMeta(data); err != nil {
+		return err
+	}
+	s.data = data
+	s.folders = slices.Clone(data.Folders)
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// writeNote writes note to its file unless the file already holds it,
+// renaming the file if the note's title or folder changed. If another
+// program changed the file since it was last read, its contents are
+// returned so they can be kept.
+func (s *MarkdownStore) writeNote(note Note, claimed map[string]bool) ([]byte, error) {
+	rendered, err := renderMarkdownNote(note)
+	if err != nil {
+		return nil, err
+	}
+	file, known := s.files[note.ID]
+	path := file.path
+	if !known || file.title != note.Title || filepath.Dir(path) != s.folderDir(note.Folder) {
+		path = s.notePath(note, claimed)
+	}
+	if path == file.path && bytes.Equal(rendered, file.content) {
+		return nil, nil
+	}
+
+	var theirs []byte
+	if known {
+		theirs, _ = s.changedOnDisk(file)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := replaceFile(path, rendered, 0644); err != nil {
+		return nil, err
+	}
+	if known && path != file.path {
+		os.Remove(file.path)
+		delete(claimed, file.path)
+	}
+	claimed[path] = true
+	s.files[note.ID] = markdownFile{path: path, title: note.Title, content: rendered}
+	return theirs, nil
+}
+
+// changedOnDisk returns the contents of a note file if another program
+// changed it since this process last read or wrote it. A file that is gone
+// counts as unchanged.
+func (s *MarkdownStore) changedOnDisk(file markdownFile) ([]byte, bool) {
+	content, err := os.ReadFile(file.path)
+	if err != nil || bytes.Equal(content, file.content) {
+		return nil, false
+	}
+	return content, true
+}
+
+// noteFromConflict reads the version of a note another program wrote to
+// path. Contents that are not a valid note file are kept as plain text.
+func (s *MarkdownStore) noteFromConflict(path string, content []byte) Note {
+	now := time.Now()
+	note, err := s.readNote(path, content, now)
+	if err != nil {
+		note = Note{
+			Title:     strings.TrimSuffix(filepath.Base(path), ".md"),
+			Content:   string(content),
+			Tags:      []string{},
+			Folder:    s.folderOf(path),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+	return note
+}
+
+// readNote parses the note file at path, filing the note in the folder of
+// the file's directory and titling it after the file if it has no title.
+func (s *MarkdownStore) readNote(path string, content []byte, modTime time.Time) (Note, error) {
+	note, err := parseMarkdownNote(content, modTime)
+	if err != nil {
+		return note, err
+	}
+	if note.Title == "" {
+		note.Title = strings.TrimSuffix(filepath.Base(path), ".md")
+	}
+	note.Folder = s.folderOf(path)
+	return note, nil
+}
+
+// folderOf returns the folder of the note file at path.
+func (s *MarkdownStore) folderOf(path string) string {
+	rel, err := filepath.Rel(s.dir, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// folderDir returns the directory holding the notes of folder.
+func (s *MarkdownStore) folderDir(folder string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(folder))
+}
+
+// notePath picks a file name for note that no other note uses.
+func (s *MarkdownStore) notePath(note Note, claimed map[string]bool) string {
+	dir := s.folderDir(note.Folder)
+	base := slugify(note.Title)
+	if base == "" {
+		base = fmt.Sprintf("note-%d", note.ID)
+	}
+	path := filepath.Join(dir, base+".md")
+	if file := s.files[note.ID]; file.path == path {
+		return path
+	}
+	if _, err := os.Stat(path); claimed[path] || err == nil {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.md", base, note.ID))
+	}
+	return path
+}
+
+func (s *MarkdownStore) readMeta() (*AppData, error) {
+	path := filepath.Join(s.dir, markdownMetaFile)
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		data := DefaultData()
+		if s.Seed != nil {
+			seed := *s.Seed
+			data = &seed
+		}
+		data.Notes = []Note{}
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var meta markdownMeta
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return nil, &CorruptError{Path: path, Err: err}
+	}
+	if meta.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("%s was written by a newer QuickNotes (schema version %d, this build supports %d)", path, meta.SchemaVersion, SchemaVersion)
+	}
+	return &AppData{
+		SchemaVersion: SchemaVersion,
+		Notes:         []Note{},
+		Folders:       meta.Folders,
+		Tags:          meta.Tags,
+		Templates:     meta.Templates,
+		Revisions:     meta.Revisions,
+		NextID:        max(meta.NextID, 1),
+		DefaultFolder: meta.DefaultFolder,
+	}, nil
+}
+
+func (s *MarkdownStore) writeMeta(data *AppData) error {
+	meta := markdownMeta{
+		SchemaVersion: SchemaVersion,
+		Folders:       data.Folders,
+		Tags:          data.Tags,
+		Templates:     data.Templates,
+		Revisions:     data.Revisions,
+		NextID:        data.NextID,
+		DefaultFolder: data.DefaultFolder,
+	}
+	content, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return replaceFile(filepath.Join(s.dir, markdownMetaFile), content, 0644)
+}
+
+// loaded returns the cached notebook, reading it from disk if needed.
+func (s *MarkdownStore) loaded() (*AppData, error) {
+	if s.data != nil {
+		return s.data, nil
+	}
+	return s.Load()
+}
+
+func (s *MarkdownStore) GetNote(id int) (Note, error) {
+	data, err := s.loaded()
+	if err != nil {
+		return Note{}, err
+	}
+	if note, ok := data.Note(id); ok {
+		return note, nil
+	}
+	return Note{}, ErrNotFound
+}
+
+func (s *MarkdownStore) PutNote(note Note) error {
+	data, err := s.loaded()
+	if err != nil {
+		return err
+	}
+	data.PutNote(note)
+	return s.Save(data)
+}
+
+func (s *MarkdownStore) DeleteNote(id int) error {
+	data, err := s.loaded()
+	if err != nil {
+		return err
+	}
+	if !data.DeleteNote(id) {
+		return ErrNotFound
+	}
+	return s.Save(data)
+}
+
+func (s *MarkdownStore) ListFolders() ([]string, error) {
+	data, err := s.loaded()
+	if err != nil {
+		return nil, err
+	}
+	return data.Folders, nil
+}
+
+func (s *MarkdownStore) ListTags() ([]string, error) {
+	data, err := s.loaded()
+	if err != nil {
+		return nil, err
+	}
+	return data.Tags, nil
+}
+
+// parseMarkdownNote reads a note file. Files without front matter are
+// accepted as plain content; modTime stands in for missing timestamps.
+func parseMarkdownNote(content []byte, modTime time.Time) (Note, error) {
+	note := Note{Tags: []string{}, CreatedAt: modTime, UpdatedAt: modTime}
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+
+	body, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		note.Content = text
+		return note, nil
+	}
+	header, rest, found := strings.Cut(body, "\n---\n")
+	if !found {
+		if header, found = strings.CutSuffix(body, "\n---"); !found {
+			note.Content = text
+			return note, nil
+		}
+	}
+
+	var fm frontMatter
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		return note, fmt.Errorf("invalid front matter: %w", err)
+	}
+	note.ID = fm.ID
+	note.Title = fm.Title
+	if fm.Tags != nil {
+		note.Tags = fm.Tags
+	}
+	if !fm.Created.IsZero() {
+		note.CreatedAt = fm.Created
+	}
+	if !fm.Updated.IsZero() {
+		note.UpdatedAt = fm.Updated
+	}
+	note.DeletedAt = fm.Deleted
+	note.Content = rest
+	return note, nil
+}
+
+// renderMarkdownNote formats a note as front matter followed by its content.
+func renderMarkdownNote(note Note) ([]byte, error) {
+	tags := note.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	header, err := yaml.Marshal(frontMatter{
+		ID:      note.ID,
+		Title:   note.Title,
+		Tags:    tags,
+		Created: note.CreatedAt,
+		Updated: note.UpdatedAt,
+		Deleted: note.DeletedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
+	buf.WriteString("---\n")
+	buf.WriteString(note.Content)
+	return buf.Bytes(), nil
+}
+
+// slugify turns a title into a file name: lower case letters and digits
+// separated by single dashes.
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
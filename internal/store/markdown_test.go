@@ -0,0 +1,147 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// markdownNotebook returns a markdown store in a new directory holding one
+// note, and the path of that note's file.
+func markdownNotebook(t *testing.T) (*MarkdownStore, *AppData, string) {
+	t.Helper()
+	dir := t.TempDir()
+	st := NewMarkdownStore(dir)
+	data, err := st.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := data.NewNote("Plan", data.DefaultFolder)
+	note.Content = "draft"
+	data.PutNote(note)
+	if err := st.Save(data); err != nil {
+		t.Fatal(err)
+	}
+	return st, data, st.files[note.ID].path
+}
+
+func TestMarkdownLoadSkipsBadFrontMatter(t *testing.T) {
+	st, _, _ := markdownNotebook(t)
+	bad := filepath.Join(st.dir, "General", "broken.md")
+	if err := os.WriteFile(bad, []byte("---\ntags: [unclosed\n---\nbody"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewMarkdownStore(st.dir)
+	data, err := reloaded.Load()
+	if err != nil {
+		t.Fatalf("Load failed because of one bad file: %v", err)
+	}
+	if len(data.Notes) != 1 {
+		t.Errorf("loaded %d notes, want 1", len(data.Notes))
+	}
+	if w := reloaded.Warnings(); len(w) != 1 || !strings.Contains(w[0], "General/broken.md") {
+		t.Errorf("Warnings() = %q, want one about General/broken.md", w)
+	}
+	if content, _ := os.ReadFile(bad); string(content) != "---\ntags: [unclosed\n---\nbody" {
+		t.Errorf("skipped file was rewritten: %q", content)
+	}
+}
+
+func TestMarkdownSaveKeepsOutsideEdits(t *testing.T) {
+	st, data, path := markdownNotebook(t)
+	note := data.Notes[len(data.Notes)-1]
+	outside := strings.Replace(mustRead(t, path), "draft", "edited in another program", 1)
+	if err := os.WriteFile(path, []byte(outside), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	note.Content = "edited here"
+	data.PutNote(note)
+	var conflict *ConflictError
+	if err := st.Save(data); !errors.As(err, &conflict) {
+		t.Fatalf("Save error = %v, want a conflict", err)
+	}
+	saved, err := NewMarkdownStore(st.dir).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := map[string]string{}
+	for _, n := range saved.Notes {
+		contents[n.Title] = n.Content
+	}
+	if contents["Plan"] != "edited here" || contents["Plan (conflicted copy)"] != "edited in another program" {
+		t.Errorf("saved notes %q, want ours and a conflicted copy of theirs", contents)
+	}
+}
+
+func TestMarkdownSaveKeepsNotesEditedAfterDelete(t *testing.T) {
+	st, data, path := markdownNotebook(t)
+	note := data.Notes[len(data.Notes)-1]
+	outside := strings.Replace(mustRead(t, path), "draft", "still needed", 1)
+	if err := os.WriteFile(path, []byte(outside), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data.DeleteNote(note.ID)
+	var conflict *ConflictError
+	if err := st.Save(data); !errors.As(err, &conflict) {
+		t.Fatalf("Save error = %v, want a conflict", err)
+	}
+	if got := mustRead(t, path); got != outside {
+		t.Errorf("file edited elsewhere was removed or rewritten: %q", got)
+	}
+	if kept, ok := data.Note(note.ID); !ok || kept.Content != "still needed" {
+		t.Errorf("note %d = %+v, want it back with the outside edit", note.ID, kept)
+	}
+}
+
+func TestMarkdownVaultsKeepTheirOwnNotes(t *testing.T) {
+	root := t.TempDir()
+	titles := map[string]string{root: "Default plan", filepath.Join(root, "vaults", "work"): "Work plan"}
+	for dir, title := range titles {
+		st, err := Open(BackendMarkdown, dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := st.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.PutNote(data.NewNote(title, data.DefaultFolder))
+		if err := st.Save(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for dir, title := range titles {
+		st, _ := Open(BackendMarkdown, dir, nil)
+		data, err := st.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, note := range data.Notes {
+			got = append(got, note.Title)
+		}
+		if len(got) != 1 || got[0] != title {
+			t.Errorf("vault in %s has notes %q, want only %q", dir, got, title)
+		}
+		for _, folder := range data.Folders {
+			if strings.HasPrefix(folder, "vaults") {
+				t.Errorf("vault in %s has folder %q from another vault", dir, folder)
+			}
+		}
+	}
+}
+
+func mustRead(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
@@ -0,0 +1,210 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Conflict describes a note that was changed both by this process and by
+// another one since it was last loaded.
+type Conflict struct {
+	NoteID int
+	Title  string
+	Reason string
+}
+
+// ConflictError is returned by Save when the data file was modified by
+// another process and some of those changes clashed with ours. The save
+// itself succeeded: both sides were kept as described by each Conflict.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	if len(e.Conflicts) == 1 {
+		c := e.Conflicts[0]
+		return fmt.Sprintf("note '%s' was also changed elsewhere: %s", c.Title, c.Reason)
+	}
+	return fmt.Sprintf("%d notes were also changed elsewhere; both versions were kept", len(e.Conflicts))
+}
+
+// merge performs a three-way merge of the notebook: base is what this
+// process last read or wrote, mine is its current state and theirs is what
+// another process has written since. Changes made on only one side are
+// applied; notes edited on both sides keep our version and get a copy of
+// theirs, so nothing is lost.
+func merge(base, mine, theirs *AppData) (*AppData, []Conflict) {
+	baseNotes := notesByID(base.Notes)
+	mineNotes := notesByID(mine.Notes)
+	theirNotes := notesByID(theirs.Notes)
+
+	nextID := max(mine.NextID, theirs.NextID)
+	for _, note := range append(slices.Clone(mine.Notes), theirs.Notes...) {
+		nextID = max(nextID, note.ID+1)
+	}
+
+	var notes, renumber []Note
+	var conflicts []Conflict
+	// Notes of ours moved to a new ID, whose history has to move with them.
+	moved := map[int]int{}
+	for _, note := range mine.Notes {
+		b, inBase := baseNotes[note.ID]
+		t, inTheirs := theirNotes[note.ID]
+		switch {
+		case !inBase && !inTheirs:
+			notes = append(notes, note)
+		case !inBase:
+			// Both sides created a note with the same ID; theirs keeps it.
+			notes = append(notes, t)
+			if !notesEqual(note, t) {
+				renumber = append(renumber, note)
+			}
+		case !inTheirs:
+			if !notesEqual(note, b) {
+				notes = append(notes, note)
+				conflicts = append(conflicts, Conflict{NoteID: note.ID, Title: note.Title, Reason: "deleted elsewhere, kept our edits"})
+			}
+		case notesEqual(note, b):
+			notes = append(notes, t)
+		case notesEqual(t, b), notesEqual(note, t):
+			notes = append(notes, note)
+		default:
+			notes = append(notes, note)
+			theirCopy := t
+			theirCopy.Title = t.Title + " (conflicted copy)"
+			renumber = append(renumber, theirCopy)
+			conflicts = append(conflicts, Conflict{NoteID: note.ID, Title: note.Title, Reason: "edited in both places, their version saved as a copy"})
+		}
+	}
+	for _, t := range theirs.Notes {
+		if _, inMine := mineNotes[t.ID]; inMine {
+			continue
+		}
+		b, inBase := baseNotes[t.ID]
+		switch {
+		case !inBase:
+			notes = append(notes, t)
+		case !notesEqual(t, b):
+			notes = append(notes, t)
+			conflicts = append(conflicts, Conflict{NoteID: t.ID, Title: t.Title, Reason: "deleted here but edited elsewhere, kept their edits"})
+		}
+	}
+	for _, note := range renumber {
+		if _, inBase := baseNotes[note.ID]; !inBase {
+			moved[note.ID] = nextID
+		}
+		note.ID = nextID
+		nextID++
+		notes = append(notes, note)
+	}
+
+	merged := *mine
+	merged.Notes = notes
+	merged.NextID = nextID
+	merged.Folders = mergeStrings(base.Folders, mine.Folders, theirs.Folders)
+	merged.Tags = mergeStrings(base.Tags, mine.Tags, theirs.Tags)
+	myRevisions := slices.Clone(mine.Revisions)
+	for i, rev := range myRevisions {
+		if id, ok := moved[rev.NoteID]; ok {
+			myRevisions[i].NoteID = id
+		}
+	}
+	merged.Revisions = mergeRevisions(base.Revisions, myRevisions, theirs.Revisions)
+	if slices.EqualFunc(mine.Templates, base.Templates, templatesEqual) {
+		merged.Templates = theirs.Templates
+	}
+	if mine.DefaultFolder == base.DefaultFolder {
+		merged.DefaultFolder = theirs.DefaultFolder
+	}
+	return &merged, conflicts
+}
+
+// mergeWithoutBase combines two copies of a notebook when the state they
+// both started from is unknown, so a change on one side cannot be told
+// from its undoing on the other. Theirs wins, and every note of ours that
+// differs from theirs is added as a conflicted copy, so nothing is lost.
+func mergeWithoutBase(mine, theirs *AppData) (*AppData, []Conflict) {
+	theirNotes := notesByID(theirs.Notes)
+	nextID := max(mine.NextID, theirs.NextID)
+	for _, note := range append(slices.Clone(mine.Notes), theirs.Notes...) {
+		nextID = max(nextID, note.ID+1)
+	}
+
+	notes := slices.Clone(theirs.Notes)
+	var conflicts []Conflict
+	moved := map[int]int{}
+	for _, note := range mine.Notes {
+		if t, ok := theirNotes[note.ID]; ok && notesEqual(note, t) {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{NoteID: nextID, Title: note.Title, Reason: "changes elsewhere could not be merged, our version saved as a copy"})
+		moved[note.ID] = nextID
+		note.ID = nextID
+		note.Title += " (conflicted copy)"
+		nextID++
+		notes = append(notes, note)
+	}
+
+	merged := *theirs
+	merged.Notes = notes
+	merged.NextID = nextID
+	merged.Folders = mergeStrings(nil, theirs.Folders, mine.Folders)
+	merged.Tags = mergeStrings(nil, theirs.Tags, mine.Tags)
+	myRevisions := slices.Clone(mine.Revisions)
+	for i, rev := range myRevisions {
+		if id, ok := moved[rev.NoteID]; ok {
+			myRevisions[i].NoteID = id
+		}
+	}
+	merged.Revisions = mergeRevisions(nil, myRevisions, theirs.Revisions)
+	return &merged, conflicts
+}
+
+// mergeStrings applies the additions and removals made by theirs to mine.
+func mergeStrings(base, mine, theirs []string) []string {
+	result := []string{}
+	for _, s := range mine {
+		if slices.Contains(base, s) && !slices.Contains(theirs, s) {
+			continue
+		}
+		result = append(result, s)
+	}
+	for _, s := range theirs {
+		if !slices.Contains(base, s) && !slices.Contains(result, s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func notesByID(notes []Note) map[int]Note {
+	byID := make(map[int]Note, len(notes))
+	for _, note := range notes {
+		byID[note.ID] = note
+	}
+	return byID
+}
+
+func notesEqual(a, b Note) bool {
+	return a.ID == b.ID &&
+		a.Title == b.Title &&
+		a.Content == b.Content &&
+		a.Folder == b.Folder &&
+		slices.Equal(a.Tags, b.Tags) &&
+		a.CreatedAt.Equal(b.CreatedAt) &&
+		a.UpdatedAt.Equal(b.UpdatedAt) &&
+		timesEqual(a.DeletedAt, b.DeletedAt)
+}
+
+// timesEqual compares optional times.
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func templatesEqual(a, b Template) bool {
+	return a.Name == b.Name && a.Content == b.Content && slices.Equal(a.Tags, b.Tags)
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAfterRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	st := NewJSONStore(path)
+	data, err := st.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := data.NewNote("Plan", data.DefaultFolder)
+	for _, content := range []string{"restored", "saved"} {
+		note.Content = content
+		data.PutNote(note)
+		if err := st.Save(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := st.Backups()
+	if err != nil || len(backups) == 0 {
+		t.Fatalf("Backups() = %v, %v", backups, err)
+	}
+
+	// The restore replaces the file this process last read
+	if err := st.Restore(backups[0]); err != nil {
+		t.Fatal(err)
+	}
+	note.Content = "unsaved"
+	data.PutNote(note)
+	var conflict *ConflictError
+	if err := st.Save(data); !errors.As(err, &conflict) || len(conflict.Conflicts) != 1 {
+		t.Fatalf("Save error = %v, want one conflict", err)
+	}
+	saved, err := NewJSONStore(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := map[string]string{}
+	for _, n := range saved.Notes {
+		contents[n.Title] = n.Content
+	}
+	if contents["Plan"] != "restored" || contents["Plan (conflicted copy)"] != "unsaved" {
+		t.Errorf("saved notes %q, want the restored note and a conflicted copy of ours", contents)
+	}
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the data file layout written by this build. Files
+// written before versioning was introduced have no schema_version and are
+// treated as version 0.
+const SchemaVersion = 2
+
+// migration upgrades a decoded data file from version from to from+1.
+type migration struct {
+	from  int
+	apply func(doc map[string]any) error
+}
+
+// migrations holds one step for every historical schema version, in order.
+// Add a step here whenever AppData, Note or Template change shape.
+var migrations = []migration{
+	{from: 0, apply: migrateV0},
+	{from: 1, apply: migrateV1},
+}
+
+// migrate upgrades content to SchemaVersion and reports the version it
+// started from.
+func migrate(path string, content []byte) ([]byte, int, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, 0, &CorruptError{Path: path, Err: err}
+	}
+
+	version := 0
+	if v, ok := doc["schema_version"].(float64); ok {
+		version = int(v)
+	}
+	if version > SchemaVersion {
+		return nil, version, fmt.Errorf("%s was written by a newer QuickNotes (schema version %d, this build supports %d)", path, version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return content, version, nil
+	}
+
+	for _, step := range migrations {
+		if step.from < version {
+			continue
+		}
+		if err := step.apply(doc); err != nil {
+			return nil, version, fmt.Errorf("upgrading %s from schema version %d: %w", path, step.from, err)
+		}
+		doc["schema_version"] = step.from + 1
+	}
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, version, err
+	}
+	return upgraded, version, nil
+}
+
+// migrateV0 upgrades files from before schema versioning. Notes created
+// without tags were written with "tags": null, and next_id could lag behind
+// the highest note ID after manual edits.
+func migrateV0(doc map[string]any) error {
+	notes, _ := doc["notes"].([]any)
+	maxID := 0.0
+	for _, n := range notes {
+		note, ok := n.(map[string]any)
+		if !ok {
+			return fmt.Errorf("malformed note: %v", n)
+		}
+		if note["tags"] == nil {
+			note["tags"] = []any{}
+		}
+		if id, ok := note["id"].(float64); ok && id > maxID {
+			maxID = id
+		}
+	}
+	if next, _ := doc["next_id"].(float64); next <= maxID {
+		doc["next_id"] = maxID + 1
+	}
+	return nil
+}
+
+// migrateV1 upgrades to version 2, which added the default folder, the
+// trash and revision history. Those fields are all optional, so nothing
+// needs to be rewritten: the default folder is chosen when the notebook is
+// next loaded (see AppData.Repair), and the version bump keeps older builds
+// from dropping the new fields.
+func migrateV1(doc map[string]any) error {
+	return nil
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// loadFixture copies testdata/name into a temporary directory and loads it
+// with the JSON backend.
+func loadFixture(t *testing.T, name string) (*AppData, string) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := NewJSONStore(path).Load()
+	if err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+	return data, path
+}
+
+func TestMigrateFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		version int
+		notes   int
+		check   func(t *testing.T, data *AppData)
+	}{
+		{
+			fixture: "v0.json",
+			version: 0,
+			notes:   2,
+			check: func(t *testing.T, data *AppData) {
+				if note, _ := data.Note(1); note.Tags == nil {
+					t.Errorf("note 1 tags = nil, want empty")
+				}
+				if data.NextID != 5 {
+					t.Errorf("NextID = %d, want 5", data.NextID)
+				}
+			},
+		},
+		{
+			fixture: "v1.json",
+			version: 1,
+			notes:   1,
+			check: func(t *testing.T, data *AppData) {
+				if len(data.Templates) != 1 || data.Templates[0].Name != "Meeting" {
+					t.Errorf("Templates = %+v, want the Meeting template", data.Templates)
+				}
+				// The default folder comes from the configuration, and
+				// no folder is made up for it
+				data.Repair("Personal")
+				if data.DefaultFolder != "Personal" {
+					t.Errorf("DefaultFolder after Repair(Personal) = %q, want Personal", data.DefaultFolder)
+				}
+				if slices.Contains(data.Folders, "General") {
+					t.Errorf("Folders = %q, want no General folder", data.Folders)
+				}
+			},
+		},
+		{
+			fixture: "v2.json",
+			version: 2,
+			notes:   2,
+			check: func(t *testing.T, data *AppData) {
+				if data.DefaultFolder != "General" {
+					t.Errorf("DefaultFolder = %q, want General", data.DefaultFolder)
+				}
+				if len(data.TrashedNotes()) != 1 || len(data.History(1)) != 1 {
+					t.Errorf("trash or history lost: %d trashed, %d revisions", len(data.TrashedNotes()), len(data.History(1)))
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, path := loadFixture(t, tt.fixture)
+			if data.SchemaVersion != SchemaVersion {
+				t.Errorf("SchemaVersion = %d, want %d", data.SchemaVersion, SchemaVersion)
+			}
+			if len(data.Notes) != tt.notes {
+				t.Errorf("got %d notes, want %d", len(data.Notes), tt.notes)
+			}
+			tt.check(t, data)
+
+			// The upgraded file is written back at the current version
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var onDisk struct {
+				SchemaVersion int `json:"schema_version"`
+			}
+			if err := json.Unmarshal(content, &onDisk); err != nil {
+				t.Fatal(err)
+			}
+			if onDisk.SchemaVersion != SchemaVersion {
+				t.Errorf("schema_version on disk = %d, want %d", onDisk.SchemaVersion, SchemaVersion)
+			}
+
+			// Older files are kept as the older build wrote them
+			backup := fmt.Sprintf("%s.bak-v%d", path, tt.version)
+			_, err = os.Stat(backup)
+			if tt.version < SchemaVersion {
+				original, _ := os.ReadFile(filepath.Join("testdata", tt.fixture))
+				if saved, err := os.ReadFile(backup); err != nil {
+					t.Errorf("no backup of the version %d file: %v", tt.version, err)
+				} else if string(saved) != string(original) {
+					t.Errorf("backup %s differs from the original file", backup)
+				}
+			} else if err == nil {
+				t.Errorf("backup %s written for a current file", backup)
+			}
+		})
+	}
+}
+
+func TestMigrateRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	content := fmt.Sprintf(`{"schema_version": %d, "notes": [], "next_id": 1}`, SchemaVersion+1)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewJSONStore(path).Load()
+	if err == nil || !strings.Contains(err.Error(), "newer QuickNotes") {
+		t.Fatalf("Load error = %v, want a newer-version error", err)
+	}
+	if saved, _ := os.ReadFile(path); string(saved) != content {
+		t.Errorf("file was rewritten after being rejected")
+	}
+	if matches, _ := filepath.Glob(path + ".bak*"); len(matches) > 0 {
+		t.Errorf("backups written for a rejected file: %v", matches)
+	}
+}
+
+func TestBackupsIncludeUpgradeCopies(t *testing.T) {
+	_, path := loadFixture(t, "v0.json")
+	st := NewJSONStore(path)
+	backups, err := st.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var upgrade *Backup
+	for i := range backups {
+		if strings.HasSuffix(backups[i].Path, ".bak-v0") {
+			upgrade = &backups[i]
+		}
+	}
+	if upgrade == nil || upgrade.Notes != 2 {
+		t.Fatalf("Backups() = %+v, want the v0 copy with 2 notes", backups)
+	}
+	if err := st.Restore(*upgrade); err != nil {
+		t.Fatal(err)
+	}
+	data, err := st.Load()
+	if err != nil {
+		t.Fatalf("loading the restored v0 copy: %v", err)
+	}
+	if data.SchemaVersion != SchemaVersion || len(data.Notes) != 2 {
+		t.Errorf("restored notebook at version %d with %d notes", data.SchemaVersion, len(data.Notes))
+	}
+}
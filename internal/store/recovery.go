@@ -0,0 +1,139 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// CorruptError reports a data file that exists but cannot be decoded.
+type CorruptError struct {
+	Path string
+	Err  error
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("%s is corrupted: %v", e.Path, e.Err)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// Backup describes an earlier copy of the notebook that can be restored.
+type Backup struct {
+	Path    string
+	ModTime time.Time
+	Notes   int
+}
+
+// Recoverer is implemented by stores that can set an unreadable notebook
+// aside and restore an earlier copy of it.
+type Recoverer interface {
+	// Quarantine moves the unreadable data out of the way, keeping it for
+	// inspection, and returns where it was moved to, or "" if it was
+	// already moved.
+	Quarantine() (string, error)
+	// Backups lists the backups that can be decoded, newest first.
+	Backups() ([]Backup, error)
+	// Restore makes the given backup the current notebook.
+	Restore(b Backup) error
+	// Reset starts a new notebook in place of the quarantined one.
+	Reset() error
+}
+
+// corruptSuffix starts the name of a quarantined data file, followed by
+// the time it was set aside.
+const corruptSuffix = ".corrupt-"
+
+// Quarantine renames the data file to data.json.corrupt-<timestamp>.
+func (s *JSONStore) Quarantine() (string, error) {
+	target := s.path + corruptSuffix + time.Now().Format("20060102-150405")
+	if err := os.Rename(s.path, target); os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	s.data, s.base = nil, nil
+	return target, nil
+}
+
+// quarantined returns the most recently quarantined data file, or "" if
+// there is none.
+func (s *JSONStore) quarantined() string {
+	matches, _ := filepath.Glob(s.path + corruptSuffix + "*")
+	if len(matches) == 0 {
+		return ""
+	}
+	// The timestamps sort by name
+	sort.Strings(matches)
+	return matches[len(matches)-1]
+}
+
+// Backups returns every readable data.json.bak* file, newest first. That
+// includes the copies kept before schema upgrades (data.json.bak-v<N>),
+// which are upgraded before being read and again when restored.
+func (s *JSONStore) Backups() ([]Backup, error) {
+	matches, err := filepath.Glob(s.path + backupSuffix + "*")
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		upgraded, _, err := migrate(path, content)
+		if err != nil {
+			continue
+		}
+		var data AppData
+		if err := json.Unmarshal(upgraded, &data); err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: path, ModTime: info.ModTime(), Notes: len(data.Notes)})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime.After(backups[j].ModTime)
+	})
+	return backups, nil
+}
+
+// Restore copies the backup over the data file.
+func (s *JSONStore) Restore(b Backup) error {
+	content, err := os.ReadFile(b.Path)
+	if err != nil {
+		return err
+	}
+	if err := replaceFile(s.path, content, 0644); err != nil {
+		return err
+	}
+	s.data, s.base = nil, nil
+	return nil
+}
+
+// Reset writes a new notebook, refusing to replace a data file that is
+// still in place.
+func (s *JSONStore) Reset() error {
+	lock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlockFile(lock)
+
+	if _, err := os.Stat(s.path); err == nil {
+		return fmt.Errorf("%s still exists; quarantine it first", s.path)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return s.write(s.seed())
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RenameFolder renames a folder in place, refiling every note in it, and
+// returns how many notes were changed. Its subfolders move along, so
+// giving it a path under another folder moves the whole subtree there.
+// The new name must not be taken; use MergeFolder to combine two folders.
+func (d *AppData) RenameFolder(from, to string) (int, error) {
+	if !slices.Contains(d.Folders, from) {
+		return 0, fmt.Errorf("folder %q does not exist", from)
+	}
+	to, err := CleanFolderPath(to)
+	if err != nil {
+		return 0, err
+	}
+	if slices.Contains(d.Folders, to) {
+		return 0, fmt.Errorf("folder %q already exists", to)
+	}
+	if InFolder(to, from) {
+		return 0, fmt.Errorf("cannot move folder %q into itself", from)
+	}
+	return d.relocate(from, to), nil
+}
+
+// MergeFolder moves every note of folder from into folder into and removes
+// from. Subfolders of from become subfolders of into, merging with those
+// of the same name. If from was the default folder, into becomes the
+// default. It returns how many notes were moved.
+func (d *AppData) MergeFolder(from, into string) (int, error) {
+	if !slices.Contains(d.Folders, from) || !slices.Contains(d.Folders, into) {
+		return 0, fmt.Errorf("both %q and %q must be existing folders", from, into)
+	}
+	if InFolder(into, from) {
+		return 0, fmt.Errorf("cannot merge folder %q into itself", from)
+	}
+	return d.relocate(from, into), nil
+}
+
+// refile moves the notes of folder from and its subfolders, trashed ones
+// included, to the same place under to, recording the edit in their
+// history.
+func (d *AppData) refile(from, to string) int {
+	changed := 0
+	for _, note := range d.Notes {
+		if InFolder(note.Folder, from) {
+			note.Folder = to + strings.TrimPrefix(note.Folder, from)
+			note.UpdatedAt = time.Now()
+			d.RecordEdit(note)
+			changed++
+		}
+	}
+	return changed
+}
+
+// RenameTag renames a tag in place, on every note carrying it too, and
+// returns how many notes were changed. The new name must not be taken;
+// use MergeTag to combine two tags.
+func (d *AppData) RenameTag(from, to string) (int, error) {
+	i := slices.Index(d.Tags, from)
+	if i < 0 {
+		return 0, fmt.Errorf("tag %q does not exist", from)
+	}
+	if slices.Contains(d.Tags, to) {
+		return 0, fmt.Errorf("tag %q already exists", to)
+	}
+	d.Tags[i] = to
+	return d.retag(from, to), nil
+}
+
+// MergeTag replaces tag from with tag into on every note and removes from.
+// It returns how many notes were changed.
+func (d *AppData) MergeTag(from, into string) (int, error) {
+	if !slices.Contains(d.Tags, from) || !slices.Contains(d.Tags, into) {
+		return 0, fmt.Errorf("both %q and %q must be existing tags", from, into)
+	}
+	changed := d.retag(from, into)
+	d.Tags = slices.DeleteFunc(d.Tags, func(t string) bool { return t == from })
+	return changed, nil
+}
+
+// retag replaces a tag on every note carrying it, keeping its position
+// and not adding it twice, and records the edit in the note's history.
+func (d *AppData) retag(from, to string) int {
+	changed := 0
+	for _, note := range d.Notes {
+		i := slices.Index(note.Tags, from)
+		if i < 0 {
+			continue
+		}
+		tags := slices.Clone(note.Tags)
+		if slices.Contains(tags, to) {
+			tags = slices.Delete(tags, i, i+1)
+		} else {
+			tags[i] = to
+		}
+		note.Tags = tags
+		note.UpdatedAt = time.Now()
+		d.RecordEdit(note)
+		changed++
+	}
+	return changed
+}
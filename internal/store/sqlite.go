@@ -0,0 +1,689 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // pure Go driver, registered as "sqlite"
+)
+
+// sqliteSchema creates the tables of a new database. notes_fts indexes the
+// searchable text of every note under the note's ID, for tools reading
+// the database; QuickNotes itself searches with package search.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS notes (
+	id         INTEGER PRIMARY KEY,
+	title      TEXT NOT NULL,
+	content    TEXT NOT NULL,
+	folder     TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS note_tags (
+	note_id  INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+	tag      TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (note_id, tag)
+);
+CREATE TABLE IF NOT EXISTS folders (
+	name     TEXT PRIMARY KEY,
+	position INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS tags (
+	name     TEXT PRIMARY KEY,
+	position INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS templates (
+	name     TEXT PRIMARY KEY,
+	content  TEXT NOT NULL,
+	tags     TEXT NOT NULL,
+	position INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS revisions (
+	id       INTEGER PRIMARY KEY,
+	note_id  INTEGER NOT NULL,
+	saved_at TEXT NOT NULL,
+	title    TEXT NOT NULL,
+	content  TEXT NOT NULL,
+	tags     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS revisions_note ON revisions (note_id);
+CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(title, content, tags);
+`
+
+// sqliteMigrations upgrade databases created by earlier versions. The
+// database's user_version records how many of them have been applied.
+var sqliteMigrations = []string{
+	`ALTER TABLE notes ADD COLUMN deleted_at TEXT`,
+	`ALTER TABLE revisions ADD COLUMN folder TEXT NOT NULL DEFAULT ''`,
+}
+
+// SQLiteStore keeps the notebook in an SQLite database. Saves only write
+// the rows that changed since the last load or save, and keep an FTS5
+// full-text index of the notes up to date. Every write bumps a generation
+// counter in the meta table, so a save that finds it moved on merges the
+// other process's changes first, as JSONStore does.
+type SQLiteStore struct {
+	// Seed is the notebook created on first run; DefaultData() if nil.
+	Seed *AppData
+
+	db   *sql.DB
+	path string
+
+	// The notebook as last read from or written to the database.
+	notes     map[int]Note
+	folders   []string
+	tags      []string
+	templates []Template
+	revisions map[revisionKey]Revision
+	nextID    int
+	// defaultFolder is empty in databases created before it was recorded.
+	defaultFolder string
+	// generation is the write counter of the database as last read or
+	// written by this process.
+	generation int
+}
+
+// sqlQuerier reads from the database or from within a transaction.
+type sqlQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// NewSQLiteStore opens (creating if needed) the database at path.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db, path: path}, nil
+}
+
+// Close releases the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Load reads the whole notebook, creating the tables and the seed
+// notebook on first run.
+func (s *SQLiteStore) Load() (*AppData, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.Exec(sqliteSchema); err != nil {
+		return nil, err
+	}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+
+	var version string
+	err := s.db.QueryRow(`SELECT value FROM meta WHERE key = 'schema_version'`).Scan(&version)
+	if err == sql.ErrNoRows {
+		data := DefaultData()
+		if s.Seed != nil {
+			seed := *s.Seed
+			data = &seed
+		}
+		s.notes = map[int]Note{}
+		if err := s.Save(data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if v, _ := strconv.Atoi(version); v > SchemaVersion {
+		return nil, fmt.Errorf("database was written by a newer QuickNotes (schema version %d, this build supports %d)", v, SchemaVersion)
+	}
+
+	// Read in one transaction so the notebook and its generation match
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	generation, err := readGeneration(tx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.read(tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.remember(data)
+	s.generation = generation
+	return data, nil
+}
+
+// read reads the whole notebook.
+func (s *SQLiteStore) read(q sqlQuerier) (*AppData, error) {
+	data := &AppData{SchemaVersion: SchemaVersion, Notes: []Note{}, Templates: []Template{}}
+	err := q.QueryRow(`SELECT value FROM meta WHERE key = 'next_id'`).Scan(&data.NextID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	data.NextID = max(data.NextID, 1)
+	err = q.QueryRow(`SELECT value FROM meta WHERE key = 'default_folder'`).Scan(&data.DefaultFolder)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if data.Folders, err = names(q, "folders"); err != nil {
+		return nil, err
+	}
+	if data.Tags, err = names(q, "tags"); err != nil {
+		return nil, err
+	}
+	if data.Templates, err = loadTemplates(q); err != nil {
+		return nil, err
+	}
+	if data.Revisions, err = loadRevisions(q); err != nil {
+		return nil, err
+	}
+	if data.Notes, err = queryNotes(q, `SELECT id, title, content, folder, created_at, updated_at, deleted_at FROM notes ORDER BY id`); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Save writes the notes that were added, changed or deleted since the
+// last load or save, and the folder, tag and template lists if they
+// changed, in a single transaction. If another process wrote to the
+// database in the meantime, its changes are merged into data first; a
+// *ConflictError is returned after saving when some of them clashed with
+// ours.
+func (s *SQLiteStore) Save(data *AppData) error {
+	if s.notes == nil {
+		if _, err := s.Load(); err != nil {
+			return err
+		}
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	generation, err := bumpGeneration(tx)
+	if err != nil {
+		return err
+	}
+	var conflicts []Conflict
+	if generation != s.generation {
+		theirs, err := s.read(tx)
+		if err != nil {
+			return err
+		}
+		var merged *AppData
+		merged, conflicts = merge(s.snapshot(), data, theirs)
+		*data = *merged
+		// What is written below is the difference from their notebook
+		s.remember(theirs)
+	}
+
+	present := map[int]bool{}
+	for _, note := range data.Notes {
+		present[note.ID] = true
+		if known, ok := s.notes[note.ID]; ok && notesEqual(known, note) {
+			continue
+		}
+		if err := putNoteTx(tx, note); err != nil {
+			return err
+		}
+	}
+	for id := range s.notes {
+		if !present[id] {
+			if err := deleteNoteTx(tx, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	inData := map[revisionKey]bool{}
+	for _, rev := range data.Revisions {
+		inData[rev.key()] = true
+		if _, ok := s.revisions[rev.key()]; !ok {
+			if err := addRevisionTx(tx, rev); err != nil {
+				return err
+			}
+		}
+	}
+	for key, rev := range s.revisions {
+		if !inData[key] {
+			if _, err := tx.Exec(`DELETE FROM revisions WHERE note_id = ? AND saved_at = ?`, rev.NoteID, rev.SavedAt.Format(time.RFC3339Nano)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !slices.Equal(s.folders, data.Folders) {
+		if err := replaceNames(tx, "folders", data.Folders); err != nil {
+			return err
+		}
+	}
+	if !slices.Equal(s.tags, data.Tags) {
+		if err := replaceNames(tx, "tags", data.Tags); err != nil {
+			return err
+		}
+	}
+	if !slices.EqualFunc(s.templates, data.Templates, templatesEqual) {
+		if err := replaceTemplates(tx, data.Templates); err != nil {
+			return err
+		}
+	}
+	data.SchemaVersion = SchemaVersion
+	if err := setMeta(tx, "schema_version", strconv.Itoa(SchemaVersion)); err != nil {
+		return err
+	}
+	if data.NextID != s.nextID {
+		if err := setMeta(tx, "next_id", strconv.Itoa(data.NextID)); err != nil {
+			return err
+		}
+	}
+	if data.DefaultFolder != s.defaultFolder {
+		if err := setMeta(tx, "default_folder", data.DefaultFolder); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.remember(data)
+	s.generation = generation + 1
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// migrate applies the schema changes the database has not seen yet.
+func (s *SQLiteStore) migrate() error {
+	var applied int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&applied); err != nil {
+		return err
+	}
+	for i := applied; i < len(sqliteMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("upgrading database: %w", err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remember records data as the state of the database.
+func (s *SQLiteStore) remember(data *AppData) {
+	s.notes = make(map[int]Note, len(data.Notes))
+	for _, note := range data.Notes {
+		note.Tags = slices.Clone(note.Tags)
+		s.notes[note.ID] = note
+	}
+	s.folders = slices.Clone(data.Folders)
+	s.tags = slices.Clone(data.Tags)
+	s.templates = slices.Clone(data.Templates)
+	s.revisions = make(map[revisionKey]Revision, len(data.Revisions))
+	for _, rev := range data.Revisions {
+		s.revisions[rev.key()] = rev
+	}
+	s.nextID = data.NextID
+	s.defaultFolder = data.DefaultFolder
+}
+
+// snapshot returns the notebook as last read from or written to the
+// database.
+func (s *SQLiteStore) snapshot() *AppData {
+	data := &AppData{
+		SchemaVersion: SchemaVersion,
+		Notes:         []Note{},
+		Folders:       s.folders,
+		Tags:          s.tags,
+		Templates:     s.templates,
+		NextID:        s.nextID,
+		DefaultFolder: s.defaultFolder,
+	}
+	for _, note := range s.notes {
+		data.Notes = append(data.Notes, note)
+	}
+	for _, rev := range s.revisions {
+		data.Revisions = append(data.Revisions, rev)
+	}
+	return data
+}
+
+func (s *SQLiteStore) GetNote(id int) (Note, error) {
+	notes, err := queryNotes(s.db, `SELECT id, title, content, folder, created_at, updated_at, deleted_at FROM notes WHERE id = ?`, id)
+	if err != nil {
+		return Note{}, err
+	}
+	if len(notes) == 0 {
+		return Note{}, ErrNotFound
+	}
+	return notes[0], nil
+}
+
+func (s *SQLiteStore) PutNote(note Note) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	generation, err := bumpGeneration(tx)
+	if err != nil {
+		return err
+	}
+	if err := putNoteTx(tx, note); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE meta SET value = ? WHERE key = 'next_id' AND CAST(value AS INTEGER) <= ?`, strconv.Itoa(note.ID+1), note.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if s.notes != nil {
+		s.notes[note.ID] = note
+	}
+	s.wrote(generation)
+	return nil
+}
+
+func (s *SQLiteStore) DeleteNote(id int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM notes WHERE id = ?`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrNotFound
+	}
+	generation, err := bumpGeneration(tx)
+	if err != nil {
+		return err
+	}
+	if err := deleteNoteTx(tx, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	delete(s.notes, id)
+	for key := range s.revisions {
+		if key.noteID == id {
+			delete(s.revisions, key)
+		}
+	}
+	s.wrote(generation)
+	return nil
+}
+
+// wrote records a write to a single note made at generation. If another
+// process wrote before it, the generation is left behind so the next Save
+// still merges that process's changes.
+func (s *SQLiteStore) wrote(generation int) {
+	if generation == s.generation {
+		s.generation = generation + 1
+	}
+}
+
+func (s *SQLiteStore) ListFolders() ([]string, error) {
+	return names(s.db, "folders")
+}
+
+func (s *SQLiteStore) ListTags() ([]string, error) {
+	return names(s.db, "tags")
+}
+
+// queryNotes runs a query selecting note columns and attaches the tags.
+func queryNotes(q sqlQuerier, query string, args ...any) ([]Note, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []Note{}
+	for rows.Next() {
+		var note Note
+		var created, updated string
+		var deleted sql.NullString
+		if err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.Folder, &created, &updated, &deleted); err != nil {
+			return nil, err
+		}
+		note.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+		note.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updated)
+		if deleted.Valid {
+			if t, err := time.Parse(time.RFC3339Nano, deleted.String); err == nil {
+				note.DeletedAt = &t
+			}
+		}
+		note.Tags = []string{}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]int, len(notes))
+	for i, note := range notes {
+		byID[note.ID] = i
+	}
+	tagRows, err := q.Query(`SELECT note_id, tag FROM note_tags ORDER BY note_id, position`)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var id int
+		var tag string
+		if err := tagRows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		if i, ok := byID[id]; ok {
+			notes[i].Tags = append(notes[i].Tags, tag)
+		}
+	}
+	return notes, tagRows.Err()
+}
+
+// names reads an ordered folder or tag list.
+func names(q sqlQuerier, table string) ([]string, error) {
+	rows, err := q.Query(`SELECT name FROM ` + table + ` ORDER BY position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func loadTemplates(q sqlQuerier) ([]Template, error) {
+	rows, err := q.Query(`SELECT name, content, tags FROM templates ORDER BY position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	templates := []Template{}
+	for rows.Next() {
+		var template Template
+		var tags string
+		if err := rows.Scan(&template.Name, &template.Content, &tags); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(tags), &template.Tags); err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+// loadRevisions reads the history of every note, in the order it was
+// recorded.
+func loadRevisions(q sqlQuerier) ([]Revision, error) {
+	rows, err := q.Query(`SELECT note_id, saved_at, title, content, tags, folder FROM revisions ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var revisions []Revision
+	for rows.Next() {
+		var rev Revision
+		var savedAt, tags string
+		if err := rows.Scan(&rev.NoteID, &savedAt, &rev.Title, &rev.Content, &tags, &rev.Folder); err != nil {
+			return nil, err
+		}
+		rev.SavedAt, _ = time.Parse(time.RFC3339Nano, savedAt)
+		if err := json.Unmarshal([]byte(tags), &rev.Tags); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// putNoteTx inserts or replaces a note with its tags and index entry.
+func putNoteTx(tx *sql.Tx, note Note) error {
+	var deleted sql.NullString
+	if note.DeletedAt != nil {
+		deleted = sql.NullString{String: note.DeletedAt.Format(time.RFC3339Nano), Valid: true}
+	}
+	_, err := tx.Exec(`INSERT INTO notes (id, title, content, folder, created_at, updated_at, deleted_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET title = excluded.title, content = excluded.content, folder = excluded.folder,
+			created_at = excluded.created_at, updated_at = excluded.updated_at, deleted_at = excluded.deleted_at`,
+		note.ID, note.Title, note.Content, note.Folder,
+		note.CreatedAt.Format(time.RFC3339Nano), note.UpdatedAt.Format(time.RFC3339Nano), deleted)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM note_tags WHERE note_id = ?`, note.ID); err != nil {
+		return err
+	}
+	for i, tag := range note.Tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO note_tags (note_id, tag, position) VALUES (?, ?, ?)`, note.ID, tag, i); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM notes_fts WHERE rowid = ?`, note.ID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO notes_fts (rowid, title, content, tags) VALUES (?, ?, ?, ?)`,
+		note.ID, note.Title, note.Content, strings.Join(note.Tags, " "))
+	return err
+}
+
+// deleteNoteTx removes a note with its tags, history and index entry.
+func deleteNoteTx(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec(`DELETE FROM notes WHERE id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM revisions WHERE note_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM notes_fts WHERE rowid = ?`, id)
+	return err
+}
+
+func addRevisionTx(tx *sql.Tx, rev Revision) error {
+	tags, err := json.Marshal(rev.Tags)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO revisions (note_id, saved_at, title, content, tags, folder) VALUES (?, ?, ?, ?, ?, ?)`,
+		rev.NoteID, rev.SavedAt.Format(time.RFC3339Nano), rev.Title, rev.Content, string(tags), rev.Folder)
+	return err
+}
+
+// replaceNames rewrites an ordered folder or tag list.
+func replaceNames(tx *sql.Tx, table string, names []string) error {
+	if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+		return err
+	}
+	for i, name := range names {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO `+table+` (name, position) VALUES (?, ?)`, name, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func replaceTemplates(tx *sql.Tx, templates []Template) error {
+	if _, err := tx.Exec(`DELETE FROM templates`); err != nil {
+		return err
+	}
+	for i, template := range templates {
+		tags, err := json.Marshal(template.Tags)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO templates (name, content, tags, position) VALUES (?, ?, ?, ?)`,
+			template.Name, template.Content, string(tags), i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readGeneration returns the write counter of the database, 0 for
+// databases that have not counted writes yet.
+func readGeneration(q sqlQuerier) (int, error) {
+	var generation int
+	err := q.QueryRow(`SELECT value FROM meta WHERE key = 'generation'`).Scan(&generation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return generation, err
+}
+
+// bumpGeneration counts a write made by tx and returns the generation the
+// database had before it.
+func bumpGeneration(tx *sql.Tx) (int, error) {
+	generation, err := readGeneration(tx)
+	if err != nil {
+		return 0, err
+	}
+	return generation, setMeta(tx, "generation", strconv.Itoa(generation+1))
+}
+
+func setMeta(tx *sql.Tx, key, value string) error {
+	_, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
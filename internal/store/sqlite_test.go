@@ -0,0 +1,91 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// openSQLite opens the database at path and loads it.
+func openSQLite(t *testing.T, path string) (*SQLiteStore, *AppData) {
+	t.Helper()
+	st, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	data, err := st.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st, data
+}
+
+func TestSQLiteSaveMergesOtherProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quicknotes.db")
+	first, firstData := openSQLite(t, path)
+	second, secondData := openSQLite(t, path)
+
+	firstData.PutNote(firstData.NewNote("From the first", firstData.DefaultFolder))
+	firstData.AddFolder("Errands")
+	if err := first.Save(firstData); err != nil {
+		t.Fatal(err)
+	}
+	secondData.PutNote(secondData.NewNote("From the second", secondData.DefaultFolder))
+	secondData.AddFolder("Reading")
+	if err := second.Save(secondData); err != nil {
+		t.Fatal(err)
+	}
+
+	_, saved := openSQLite(t, path)
+	var titles []string
+	for _, note := range saved.Notes {
+		titles = append(titles, note.Title)
+	}
+	for _, title := range []string{"From the first", "From the second"} {
+		if !slices.Contains(titles, title) {
+			t.Errorf("notes after both saves = %q, missing %q", titles, title)
+		}
+	}
+	for _, folder := range []string{"Errands", "Reading"} {
+		if !slices.Contains(saved.Folders, folder) {
+			t.Errorf("folders after both saves = %q, missing %q", saved.Folders, folder)
+		}
+	}
+}
+
+func TestSQLiteSaveReportsConflicts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quicknotes.db")
+	first, firstData := openSQLite(t, path)
+	note := firstData.NewNote("Plan", firstData.DefaultFolder)
+	firstData.PutNote(note)
+	if err := first.Save(firstData); err != nil {
+		t.Fatal(err)
+	}
+	second, secondData := openSQLite(t, path)
+
+	note.Content = "first version"
+	firstData.PutNote(note)
+	if err := first.Save(firstData); err != nil {
+		t.Fatal(err)
+	}
+	note.Content = "second version"
+	secondData.PutNote(note)
+	var conflict *ConflictError
+	if err := second.Save(secondData); !errors.As(err, &conflict) {
+		t.Fatalf("Save error = %v, want a conflict", err)
+	}
+
+	_, saved := openSQLite(t, path)
+	var contents []string
+	for _, n := range saved.Notes {
+		if n.Title == "Plan" || n.Title == "Plan (conflicted copy)" {
+			contents = append(contents, n.Content)
+		}
+	}
+	slices.Sort(contents)
+	if !slices.Equal(contents, []string{"first version", "second version"}) {
+		t.Errorf("saved versions = %q, want both", contents)
+	}
+}
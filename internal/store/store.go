@@ -0,0 +1,192 @@
+// Package store defines the QuickNotes data model and the backends that
+// persist it.
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Data structures
+type Note struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	Folder    string    `json:"folder"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set while the note is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type AppData struct {
+	SchemaVersion int        `json:"schema_version"`
+	Notes         []Note     `json:"notes"`
+	Folders       []string   `json:"folders"`
+	Tags          []string   `json:"tags"`
+	Templates     []Template `json:"templates"`
+	Revisions     []Revision `json:"revisions,omitempty"`
+	NextID        int        `json:"next_id"`
+	// DefaultFolder is where new notes are filed and where notes go when
+	// their folder is deleted. It always exists and cannot be deleted, but
+	// it can be renamed or another folder can be made the default.
+	DefaultFolder string `json:"default_folder"`
+}
+
+type Template struct {
+	Name    string   `json:"name"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags"`
+}
+
+// ErrNotFound is returned when a note with the requested ID does not exist.
+var ErrNotFound = errors.New("note not found")
+
+// Store is implemented by every storage backend.
+type Store interface {
+	// Load reads the whole notebook, creating a default one if none exists.
+	Load() (*AppData, error)
+	// Save persists the whole notebook.
+	Save(data *AppData) error
+
+	GetNote(id int) (Note, error)
+	// PutNote inserts the note or replaces the one with the same ID.
+	PutNote(note Note) error
+	DeleteNote(id int) error
+
+	ListFolders() ([]string, error)
+	ListTags() ([]string, error)
+}
+
+// Warner is implemented by stores that can load a notebook in part,
+// leaving out what they could not read.
+type Warner interface {
+	// Warnings describes what the last Load left out.
+	Warnings() []string
+}
+
+// Summary describes where a note is filed and when it was created, as
+// shown under its title in note lists.
+func (n Note) Summary() string {
+	return fmt.Sprintf("📁 %s | 🏷️ %s | %s", n.Folder, strings.Join(n.Tags, ", "), n.CreatedAt.Format("2006-01-02"))
+}
+
+// NewNote returns an empty note with the next free ID. The note is not
+// added to Notes; that happens when it is first saved.
+func (d *AppData) NewNote(title, folder string) Note {
+	now := time.Now()
+	note := Note{
+		ID:        d.NextID,
+		Title:     title,
+		Tags:      []string{},
+		Folder:    folder,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	d.NextID++
+	return note
+}
+
+// Note returns the note with the given ID.
+func (d *AppData) Note(id int) (Note, bool) {
+	for _, note := range d.Notes {
+		if note.ID == id {
+			return note, true
+		}
+	}
+	return Note{}, false
+}
+
+// PutNote replaces the note with the same ID, or adds it if there is none.
+func (d *AppData) PutNote(note Note) {
+	for i := range d.Notes {
+		if d.Notes[i].ID == note.ID {
+			d.Notes[i] = note
+			return
+		}
+	}
+	d.Notes = append(d.Notes, note)
+	if note.ID >= d.NextID {
+		d.NextID = note.ID + 1
+	}
+}
+
+// DeleteNote removes the note with the given ID and its history,
+// reporting whether it existed.
+func (d *AppData) DeleteNote(id int) bool {
+	for i := range d.Notes {
+		if d.Notes[i].ID == id {
+			d.Notes = append(d.Notes[:i], d.Notes[i+1:]...)
+			d.Revisions = slices.DeleteFunc(d.Revisions, func(rev Revision) bool { return rev.NoteID == id })
+			return true
+		}
+	}
+	return false
+}
+
+// Backends accepted by Open.
+const (
+	BackendJSON     = "json"
+	BackendMarkdown = "markdown"
+	BackendSQLite   = "sqlite"
+)
+
+// Open returns the store of the named backend keeping its files in dir.
+// seed is the notebook created on first run.
+func Open(backend, dir string, seed *AppData) (Store, error) {
+	switch backend {
+	case BackendJSON, "":
+		st := NewJSONStore(filepath.Join(dir, "data.json"))
+		st.Seed = seed
+		return st, nil
+	case BackendMarkdown:
+		// The notes get a directory of their own, as dir also holds the
+		// other vaults under vaults/
+		st := NewMarkdownStore(filepath.Join(dir, "notes"))
+		st.Seed = seed
+		return st, nil
+	case BackendSQLite:
+		st, err := NewSQLiteStore(filepath.Join(dir, "quicknotes.db"))
+		if err != nil {
+			return nil, err
+		}
+		st.Seed = seed
+		return st, nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", backend)
+}
+
+// DefaultPath returns the location of the data file (cross-platform).
+func DefaultPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".quicknotes", "data.json")
+}
+
+// DefaultData returns the notebook a first run starts with.
+func DefaultData() *AppData {
+	return &AppData{
+		SchemaVersion: SchemaVersion,
+		Notes:         []Note{},
+		Folders:       []string{"General", "Work", "Personal"},
+		Tags:          []string{"important", "todo", "idea"},
+		Templates:     DefaultTemplates(),
+		NextID:        1,
+		DefaultFolder: "General",
+	}
+}
+
+// DefaultTemplates returns the built-in note templates.
+func DefaultTemplates() []Template {
+	return []Template{
+		{Name: "Meeting Notes", Content: "# Meeting Notes...", Tags: []string{"meeting", "work"}},
+		{Name: "Daily Journal", Content: "# Daily Journal...", Tags: []string{"journal", "personal"}},
+		{Name: "Project Planning", Content: "# Project Plan...", Tags: []string{"project", "planning", "work"}},
+		{Name: "Quick Idea", Content: "# Idea...", Tags: []string{"idea", "brainstorm"}},
+	}
+}
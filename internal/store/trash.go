@@ -0,0 +1,81 @@
+package store
+
+import (
+	"slices"
+	"time"
+)
+
+// Trashed reports whether the note is in the trash.
+func (n Note) Trashed() bool {
+	return n.DeletedAt != nil
+}
+
+// LiveNotes returns the notes that are not in the trash.
+func (d *AppData) LiveNotes() []Note {
+	var notes []Note
+	for _, note := range d.Notes {
+		if !note.Trashed() {
+			notes = append(notes, note)
+		}
+	}
+	return notes
+}
+
+// TrashedNotes returns the notes in the trash, most recently deleted first.
+func (d *AppData) TrashedNotes() []Note {
+	var notes []Note
+	for _, note := range d.Notes {
+		if note.Trashed() {
+			notes = append(notes, note)
+		}
+	}
+	slices.SortStableFunc(notes, func(a, b Note) int {
+		return b.DeletedAt.Compare(*a.DeletedAt)
+	})
+	return notes
+}
+
+// TrashNote moves the note with the given ID to the trash, reporting
+// whether it existed and was not trashed already.
+func (d *AppData) TrashNote(id int) bool {
+	for i := range d.Notes {
+		if d.Notes[i].ID == id && !d.Notes[i].Trashed() {
+			now := time.Now()
+			d.Notes[i].DeletedAt = &now
+			return true
+		}
+	}
+	return false
+}
+
+// RestoreNote takes the note with the given ID out of the trash, reporting
+// whether it was there.
+func (d *AppData) RestoreNote(id int) bool {
+	for i := range d.Notes {
+		if d.Notes[i].ID == id && d.Notes[i].Trashed() {
+			d.Notes[i].DeletedAt = nil
+			return true
+		}
+	}
+	return false
+}
+
+// PurgeTrash permanently deletes the notes that have been in the trash for
+// longer than retention and returns how many were deleted. A retention of
+// zero or less keeps trashed notes forever.
+func (d *AppData) PurgeTrash(retention time.Duration) int {
+	if retention <= 0 {
+		return 0
+	}
+	cutoff := time.Now().Add(-retention)
+	var expired []int
+	for _, note := range d.Notes {
+		if note.Trashed() && note.DeletedAt.Before(cutoff) {
+			expired = append(expired, note.ID)
+		}
+	}
+	for _, id := range expired {
+		d.DeleteNote(id)
+	}
+	return len(expired)
+}
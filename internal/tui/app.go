@@ -2,16 +2,61 @@ package tui
 
 import (
 	"fmt"
-	"os"
+	"time"
 
+	"github.com/2004-nikhil/quicknotes/internal/store"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// Options customise the TUI.
+type Options struct {
+	// DefaultFolder becomes the notebook's default folder if it has none
+	// yet; see store.AppData.DefaultFolder.
+	DefaultFolder string
+	// Theme names a built-in palette; Colors overrides single colors of it.
+	Theme  string
+	Colors map[string]string
+	// Editor is the external editor command; $VISUAL/$EDITOR if empty.
+	Editor string
+	// Vault names the open notebook; Vaults, if set, enables switching
+	// between notebooks from the main menu.
+	Vault  string
+	Vaults Vaults
+	// TrashRetention is how long deleted notes stay in the trash before
+	// they are purged at startup; zero keeps them forever.
+	TrashRetention time.Duration
+}
+
+// Vaults lists, opens and creates named notebooks.
+type Vaults interface {
+	Names() []string
+	Open(name string) (store.Store, error)
+	Create(name string) error
+}
+
 // Run is the main entrypoint for the TUI application.
-func Run() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Alas, there's been an error: %v", err)
-		os.Exit(1)
+func Run(st store.Store, opts Options) error {
+	if opts.Theme != "" || len(opts.Colors) > 0 {
+		theme := opts.Theme
+		if theme == "" {
+			theme = "default"
+		}
+		if err := applyTheme(theme, opts.Colors); err != nil {
+			return err
+		}
+	}
+	m, err := initialModel(st, opts)
+	if err != nil {
+		return fmt.Errorf("could not load notes: %w", err)
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	final, err := p.Run()
+	// st belongs to the caller, but a vault switched to is ours to close
+	if last, ok := final.(model); ok && last.store != st {
+		closeStore(last.store)
+	}
+	if err != nil {
+		return fmt.Errorf("alas, there's been an error: %w", err)
 	}
+	return nil
 }
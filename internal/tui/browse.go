@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+)
+
+// noteFilter limits the note list to one folder, subfolders included, or
+// to one tag while browsing from the folder or tag manager.
+type noteFilter struct {
+	kind string // "folder", "tag", or "" for all notes
+	name string
+
+	// The manager the list was opened from and its cursor position
+	returnTo    viewState
+	returnIndex int
+}
+
+// matches reports whether a note passes the filter.
+func (f noteFilter) matches(note store.Note) bool {
+	switch f.kind {
+	case "folder":
+		return store.InFolder(note.Folder, f.name)
+	case "tag":
+		return slices.Contains(note.Tags, f.name)
+	}
+	return true
+}
+
+// title names the filtered list.
+func (f noteFilter) title() string {
+	switch f.kind {
+	case "folder":
+		return fmt.Sprintf("Notes in 📁 %s", f.name)
+	case "tag":
+		return fmt.Sprintf("Notes tagged 🏷️  %s", f.name)
+	}
+	return "Your Notes"
+}
+
+// browse opens the note list filtered to a folder or tag of the current
+// manager view.
+func (m model) browse(kind, name string) model {
+	m.filter = noteFilter{kind: kind, name: name, returnTo: m.state, returnIndex: m.list.Index()}
+	m.state = noteListView
+	m.message, m.messageType = "", ""
+	return m.loadNoteList()
+}
+
+// closeBrowse returns from a filtered note list to the manager it was
+// opened from.
+func (m model) closeBrowse() model {
+	m.state = m.filter.returnTo
+	m = m.loadList()
+	m.list.Select(m.filter.returnIndex)
+	m.filter = noteFilter{}
+	return m
+}
@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// confirmation is a destructive action waiting for the user to pick one
+// of its options or cancel.
+type confirmation struct {
+	action  string // "trash_note", "purge_note", "delete_folder", "delete_tag", "merge_folder", "merge_tag", "move_folder"
+	noteID  int
+	name    string // folder or tag the action applies to
+	title   string
+	details []string // what will be affected
+	options []confirmOption
+
+	// The view the dialog was opened from and its cursor position
+	returnTo    viewState
+	returnIndex int
+}
+
+// confirmOption is one way of going ahead with a confirmation.
+type confirmOption struct {
+	label, desc string
+	choice      string
+}
+
+// askConfirmation opens the confirm dialog for c. The cursor starts on
+// Cancel, so nothing happens unless an option is picked explicitly.
+func (m model) askConfirmation(c confirmation) model {
+	c.returnTo, c.returnIndex = m.state, m.list.Index()
+	m.confirm = c
+	m.state = confirmView
+	items := []list.Item{item{title: "↩️  Cancel", desc: "Keep everything as it is", id: -1}}
+	for i, option := range c.options {
+		items = append(items, item{title: option.label, desc: option.desc, id: i})
+	}
+	m.list = m.createList()
+	// Long lists of details can leave no room on short terminals
+	m.list.SetHeight(max(m.height-8-len(c.details), 1))
+	m.list.Title = "Choose"
+	m.list.SetItems(items)
+	return m
+}
+
+// updateConfirm handles keypresses in the confirm dialog.
+func (m model) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		return m.closeConfirmation(), nil
+	case "enter":
+		i, ok := m.list.SelectedItem().(item)
+		if !ok || i.id == -1 {
+			return m.closeConfirmation(), nil
+		}
+		c := m.confirm
+		m = m.closeConfirmation()
+		return m.confirmed(c, c.options[i.id].choice), nil
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// closeConfirmation returns to the view the dialog was opened from.
+func (m model) closeConfirmation() model {
+	m.state = m.confirm.returnTo
+	m = m.loadList()
+	m.list.Select(m.confirm.returnIndex)
+	m.confirm = confirmation{}
+	return m
+}
+
+// confirmed carries out the action of c with the chosen option.
+func (m model) confirmed(c confirmation, choice string) model {
+	switch c.action {
+	case "trash_note":
+		return m.trashNote(c.noteID)
+	case "purge_note":
+		return m.purgeNote(c.noteID)
+	case "delete_folder":
+		return m.deleteFolder(c.name, choice)
+	case "delete_tag":
+		return m.deleteTag(c.name)
+	case "merge_folder", "merge_tag":
+		return m.merge(c.action, c.name, choice)
+	case "move_folder":
+		return m.moveFolder(c.name, choice)
+	}
+	return m
+}
+
+// loadList prepares the list of the current view, for the views a
+// confirm dialog can be opened from.
+func (m model) loadList() model {
+	switch m.state {
+	case noteListView:
+		return m.loadNoteList()
+	case trashView:
+		return m.loadTrashList()
+	case folderManageView:
+		return m.loadFolderList()
+	case tagManageView:
+		return m.loadTagList()
+	}
+	return m
+}
+
+// reloadList rebuilds the list of the current view after the notebook
+// changed, keeping the cursor in place.
+func (m model) reloadList() model {
+	index := m.list.Index()
+	m = m.loadList()
+	m.list.Select(min(index, len(m.list.Items())-1))
+	return m
+}
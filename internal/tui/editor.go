@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/2004-nikhil/quicknotes/internal/store"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editorFinishedMsg is sent when the external editor exits.
+type editorFinishedMsg struct {
+	note     store.Note
+	path     string
+	original string
+	err      error
+}
+
+// editorCommand builds the command that opens path in the configured
+// editor, or else the one from $VISUAL or $EDITOR. The command may include
+// arguments, as in "code --wait". Settings that are blank are skipped.
+func editorCommand(editor, path string) *exec.Cmd {
+	args := []string{"vi"}
+	if runtime.GOOS == "windows" {
+		args = []string{"notepad"}
+	}
+	for _, candidate := range []string{editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			args = fields
+			break
+		}
+	}
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+// openExternalEditor writes content to a temporary file and suspends the
+// TUI while the user edits it.
+func (m model) openExternalEditor(note store.Note, content string) (model, tea.Cmd) {
+	f, err := os.CreateTemp("", fmt.Sprintf("quicknotes-%d-*.md", note.ID))
+	if err != nil {
+		m.message, m.messageType = fmt.Sprintf("Could not open editor: %v", err), "error"
+		return m, nil
+	}
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		m.message, m.messageType = fmt.Sprintf("Could not open editor: %v", err), "error"
+		return m, nil
+	}
+
+	path := f.Name()
+	return m, tea.ExecProcess(editorCommand(m.options.Editor, path), func(err error) tea.Msg {
+		return editorFinishedMsg{note: note, path: path, original: content, err: err}
+	})
+}
+
+// updateEditorFinished reads back the file written by the external editor
+// and saves the note if its content changed.
+func (m model) updateEditorFinished(msg editorFinishedMsg) (tea.Model, tea.Cmd) {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		m.message, m.messageType = fmt.Sprintf("Editor failed: %v", msg.err), "error"
+		return m, nil
+	}
+	edited, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.message, m.messageType = fmt.Sprintf("Could not read edited note: %v", err), "error"
+		return m, nil
+	}
+	if string(edited) == msg.original {
+		m.message, m.messageType = "No changes made in editor.", "warning"
+		return m, nil
+	}
+
+	if m.state != noteEditView {
+		note := msg.note
+		m.currentNote = &note
+	}
+	m.currentNote.Content = string(edited)
+	m = m.commitCurrentNote()
+	if m.state == noteEditView {
+		m.textArea.SetValue(m.currentNote.Content)
+	} else {
+		m = m.loadNoteList()
+	}
+	m, _ = m.saveData("Note saved from editor!")
+	return m, nil
+}
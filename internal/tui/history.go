@@ -0,0 +1,164 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/2004-nikhil/quicknotes/internal/diff"
+	"github.com/2004-nikhil/quicknotes/internal/store"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openHistory shows the revisions of the note with the given ID.
+func (m model) openHistory(id int) model {
+	note, ok := m.data.Note(id)
+	if !ok {
+		return m
+	}
+	m.currentNote = &note
+	m.historyMark = -1
+	m.state = historyView
+	return m.loadHistoryList()
+}
+
+// loadHistoryList prepares the list of the current note's revisions,
+// newest first. Item IDs are revision numbers, starting at 1 for the
+// oldest.
+func (m model) loadHistoryList() model {
+	m.revisions = m.data.History(m.currentNote.ID)
+	items := []list.Item{}
+	for n := len(m.revisions); n >= 1; n-- {
+		rev := m.revisions[n-1]
+		title := fmt.Sprintf("#%d  %s", n, rev.Title)
+		if n == m.historyMark {
+			title = "● " + title
+		}
+		desc := fmt.Sprintf("%s | %d lines | 🏷️ %s", rev.SavedAt.Format("2006-01-02 15:04:05"), lineCount(rev.Content), strings.Join(rev.Tags, ", "))
+		items = append(items, item{title: title, desc: desc, id: n})
+	}
+	m.list = m.createList()
+	m.list.Title = fmt.Sprintf("History: %s", m.currentNote.Title)
+	m.list.SetItems(items)
+	return m
+}
+
+// updateHistory handles keypresses in the revision history view.
+func (m model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.state = noteListView
+		m = m.loadNoteList()
+		return m, nil
+	case " ":
+		if i, ok := m.list.SelectedItem().(item); ok {
+			if m.historyMark == i.id {
+				m.historyMark = -1
+			} else {
+				m.historyMark = i.id
+			}
+			index := m.list.Index()
+			m = m.loadHistoryList()
+			m.list.Select(index)
+		}
+		return m, nil
+	case "enter":
+		if i, ok := m.list.SelectedItem().(item); ok {
+			return m.showDiff(i.id), nil
+		}
+	case "r":
+		if i, ok := m.list.SelectedItem().(item); ok {
+			note, restored := m.data.RestoreRevision(m.revisions[i.id-1])
+			if !restored {
+				break
+			}
+			m.currentNote = &note
+			m.historyMark = -1
+			m = m.loadHistoryList()
+			m, _ = m.saveData(fmt.Sprintf("Restored revision #%d as revision #%d", i.id, len(m.revisions)))
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// showDiff opens the diff of revision n against the marked revision, or
+// against the revision before it if none is marked.
+func (m model) showDiff(n int) model {
+	from, to := n-1, n
+	if m.historyMark > 0 && m.historyMark != n {
+		from = m.historyMark
+	}
+	if from > to {
+		from, to = to, from
+	}
+
+	fromText, fromName := "", "(empty)"
+	if from > 0 {
+		fromText, fromName = revisionText(m.revisions[from-1]), fmt.Sprintf("revision #%d", from)
+	}
+	toText, toName := revisionText(m.revisions[to-1]), fmt.Sprintf("revision #%d", to)
+
+	text := diff.Unified(fromText, toText, fromName, toName)
+	if text == "" {
+		text = fmt.Sprintf("%s and %s are identical.", fromName, toName)
+	}
+	m.diffTitle = fmt.Sprintf("Changes from %s to %s", fromName, toName)
+	m.diffPort = viewport.New(m.width, max(m.height-8, 5))
+	m.diffPort.SetContent(colorDiff(text))
+	m.state = diffView
+	return m
+}
+
+// updateDiff handles keypresses while a diff is shown.
+func (m model) updateDiff(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.state = historyView
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.diffPort, cmd = m.diffPort.Update(msg)
+	return m, cmd
+}
+
+// lineCount returns the number of lines in text.
+func lineCount(text string) int {
+	if text == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(text, "\n"), "\n") + 1
+}
+
+// revisionText renders a revision for diffing, so that title, folder and
+// tag changes show up alongside content changes.
+func revisionText(rev store.Revision) string {
+	return fmt.Sprintf("Title: %s\nFolder: %s\nTags: %s\n\n%s", rev.Title, rev.Folder, strings.Join(rev.Tags, ", "), rev.Content)
+}
+
+// colorDiff highlights added, removed and hunk header lines.
+func colorDiff(text string) string {
+	added := lipgloss.NewStyle().Foreground(successColor)
+	removed := lipgloss.NewStyle().Foreground(errorColor)
+	hunk := lipgloss.NewStyle().Foreground(accentColor)
+	header := lipgloss.NewStyle().Foreground(subtleColor).Bold(true)
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case i < 2: // --- and +++ file headers
+			lines[i] = header.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = added.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = removed.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = hunk.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,30 +1,44 @@
 package tui
 
 import (
-    "github.com/charmbracelet/bubbles/list"
-    "github.com/charmbracelet/bubbles/textarea"
-    "github.com/charmbracelet/bubbles/textinput"
-    tea "github.com/charmbracelet/bubbletea"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/2004-nikhil/quicknotes/internal/search"
+	"github.com/2004-nikhil/quicknotes/internal/store"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 // Application states
 type viewState int
 
 const (
-    mainMenuView viewState = iota
-    noteListView
-    noteEditView
-    searchView
-    folderManageView
-    tagManageView
-    templateView
-    inputDialogView
+	mainMenuView viewState = iota
+	noteListView
+	noteEditView
+	searchView
+	folderManageView
+	tagManageView
+	templateView
+	inputDialogView
+	noteMetaView
+	recoveryView
+	vaultView
+	historyView
+	diffView
+	trashView
+	confirmView
 )
 
 // List item for Charm's list component
 type item struct {
-    title, desc string
-    id          int
+	title, desc string
+	id          int
 }
 
 func (i item) FilterValue() string { return i.title }
@@ -33,40 +47,128 @@ func (i item) Description() string { return i.desc }
 
 // Model for the application
 type model struct {
-    state         viewState
-    data          *AppData
-    list          list.Model
-    textInput     textinput.Model
-    textArea      textarea.Model
-    currentNote   *Note
-    message       string
-    messageType   string // "success", "error", "warning"
-    width, height int
-    inputMode     string // "note_title", "folder_name", "tag_name"
-    previousState viewState
+	state         viewState
+	options       Options
+	store         store.Store
+	data          *store.AppData
+	list          list.Model
+	textInput     textinput.Model
+	textArea      textarea.Model
+	currentNote   *store.Note
+	message       string
+	messageType   string // "success", "error", "warning"
+	width, height int
+	inputMode     string // "note_title", "folder_name", "tag_name", "meta_tag_name", "vault_name", "rename_folder", "rename_tag"
+	previousState viewState
+
+	// Folder or tag being renamed and its position in the list
+	renameFrom  string
+	renameIndex int
+
+	// Folders whose subfolders are hidden in the folder tree
+	collapsed map[string]bool
+
+	// Folder or tag the note list is limited to
+	filter noteFilter
+
+	// Full-text index of the live notes, kept current by saveData
+	index *search.Index
+	// Whether the search view tolerates typos instead of parsing queries
+	fuzzySearch bool
+
+	// Pending tag/folder selection while the note metadata panel is open
+	metaTags   []string
+	metaFolder string
+	metaField  string // "tags", "folder"
+	metaReturn viewState
+
+	// Set when the notebook could not be read at startup
+	recoverer   store.Recoverer
+	loadErr     error
+	quarantined string
+	backups     []store.Backup
+
+	// Revision history of currentNote; historyMark is the revision picked
+	// as the base of a diff, or -1
+	revisions   []store.Revision
+	historyMark int
+	diffTitle   string
+	diffPort    viewport.Model
+
+	// Pending destructive action shown in the confirm dialog
+	confirm confirmation
 }
 
 // Initialize the application
-func initialModel() model {
-    data := loadData()
-    ti := textinput.New()
-    ti.Placeholder = "Enter text..."
-    ti.Focus()
-    ta := textarea.New()
-    ta.SetWidth(80)
-    ta.SetHeight(20)
-    ta.Focus()
-    m := model{
-        state:     mainMenuView,
-        data:      data,
-        textInput: ti,
-        textArea:  ta,
-    }
-    m = m.loadMainMenu() // Load initial menu
-    return m
+func initialModel(st store.Store, opts Options) (model, error) {
+	if opts.DefaultFolder == "" {
+		opts.DefaultFolder = "General"
+	}
+	ti := textinput.New()
+	ti.Placeholder = "Enter text..."
+	ti.Focus()
+	ta := textarea.New()
+	ta.SetWidth(80)
+	ta.SetHeight(20)
+	ta.Focus()
+	m := model{
+		state:     mainMenuView,
+		options:   opts,
+		textInput: ti,
+		textArea:  ta,
+	}
+	m, err := m.openStore(st)
+	if err != nil {
+		return model{}, err
+	}
+	return m, nil
+}
+
+// openStore loads the notebook from st and shows the main menu, or the
+// recovery screen if the notebook is damaged.
+func (m model) openStore(st store.Store) (model, error) {
+	data, err := st.Load()
+	var corrupt *store.CorruptError
+	if rec, ok := st.(store.Recoverer); ok && errors.As(err, &corrupt) {
+		m.store = st
+		return m.startRecovery(rec, err), nil
+	}
+	if err != nil {
+		return m, err
+	}
+	m.store = st
+	m.data = data
+	m.index = search.NewIndex(data.LiveNotes())
+	m.state = mainMenuView
+	m = m.loadMainMenu() // Load initial menu
+	var notices []string
+	if purged := data.PurgeTrash(m.options.TrashRetention); purged > 0 {
+		notices = append(notices, fmt.Sprintf("Permanently deleted %d note(s) that were in the trash for too long", purged))
+	}
+	if fixes := data.Repair(m.options.DefaultFolder); len(fixes) > 0 {
+		if len(fixes) > 3 {
+			fixes = append(fixes[:3], fmt.Sprintf("and %d more", len(fixes)-3))
+		}
+		notices = append(notices, "Repaired the notebook: "+strings.Join(fixes, ", "))
+	}
+	if len(notices) > 0 {
+		m, _ = m.saveData(strings.Join(notices, ". "))
+	}
+	if w, ok := st.(store.Warner); ok && len(w.Warnings()) > 0 {
+		warnings := w.Warnings()
+		if len(warnings) > 3 {
+			warnings = append(warnings[:3:3], fmt.Sprintf("and %d more", len(warnings)-3))
+		}
+		message := "Some notes could not be read: " + strings.Join(warnings, ", ")
+		if m.message != "" {
+			message += ". " + m.message
+		}
+		m.message, m.messageType = message, "warning"
+	}
+	return m, nil
 }
 
 // Init is the first command that will be executed.
 func (m model) Init() tea.Cmd {
-    return nil
-}
\ No newline at end of file
+	return nil
+}
@@ -1,26 +1,109 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 // Color definitions
 var (
-    primaryColor   = lipgloss.Color("#ff6b9d")
-    secondaryColor = lipgloss.Color("#4ecdc4")
-    accentColor    = lipgloss.Color("#45b7d1")
-    successColor   = lipgloss.Color("#96ceb4")
-    warningColor   = lipgloss.Color("#ffeaa7")
-    errorColor     = lipgloss.Color("#fd79a8")
-    textColor      = lipgloss.Color("#2d3436")
-    subtleColor    = lipgloss.Color("#636e72")
+	primaryColor   lipgloss.Color
+	secondaryColor lipgloss.Color
+	accentColor    lipgloss.Color
+	successColor   lipgloss.Color
+	warningColor   lipgloss.Color
+	errorColor     lipgloss.Color
+	textColor      lipgloss.Color
+	subtleColor    lipgloss.Color
 )
 
 // Styles
 var (
-    titleStyle        = lipgloss.NewStyle().Foreground(primaryColor).Bold(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(primaryColor).Padding(0, 1)
-    headerStyle       = lipgloss.NewStyle().Foreground(secondaryColor).Bold(true).Margin(1, 0)
-    itemStyle         = lipgloss.NewStyle().Foreground(textColor).Padding(0, 1)
-    selectedItemStyle = lipgloss.NewStyle().Foreground(primaryColor).Background(lipgloss.Color("#ffffff")).Bold(true).Padding(0, 1)
-    tagStyle          = lipgloss.NewStyle().Foreground(accentColor).Background(lipgloss.Color("#e8f4fd")).Padding(0, 1).Margin(0, 1)
-    folderStyle       = lipgloss.NewStyle().Foreground(successColor).Bold(true)
-    helpStyle         = lipgloss.NewStyle().Foreground(subtleColor).Margin(1, 0)
-)
\ No newline at end of file
+	titleStyle        lipgloss.Style
+	headerStyle       lipgloss.Style
+	itemStyle         lipgloss.Style
+	selectedItemStyle lipgloss.Style
+	tagStyle          lipgloss.Style
+	folderStyle       lipgloss.Style
+	helpStyle         lipgloss.Style
+)
+
+// themes are the built-in color palettes, keyed by the names accepted by
+// the "theme" setting. Each maps a color name to its value.
+var themes = map[string]map[string]string{
+	"default": {
+		"primary":   "#ff6b9d",
+		"secondary": "#4ecdc4",
+		"accent":    "#45b7d1",
+		"success":   "#96ceb4",
+		"warning":   "#ffeaa7",
+		"error":     "#fd79a8",
+		"text":      "#2d3436",
+		"subtle":    "#636e72",
+	},
+	"ocean": {
+		"primary":   "#0984e3",
+		"secondary": "#00cec9",
+		"accent":    "#74b9ff",
+		"success":   "#55efc4",
+		"warning":   "#fdcb6e",
+		"error":     "#d63031",
+		"text":      "#2d3436",
+		"subtle":    "#636e72",
+	},
+	"mono": {
+		"primary":   "15",
+		"secondary": "7",
+		"accent":    "7",
+		"success":   "15",
+		"warning":   "15",
+		"error":     "15",
+		"text":      "7",
+		"subtle":    "8",
+	},
+}
+
+func init() {
+	applyTheme("default", nil)
+}
+
+// applyTheme switches to a built-in palette, replacing individual colors
+// with any overrides, and rebuilds the styles.
+func applyTheme(name string, overrides map[string]string) error {
+	palette, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q", name)
+	}
+	colors := map[string]*lipgloss.Color{
+		"primary":   &primaryColor,
+		"secondary": &secondaryColor,
+		"accent":    &accentColor,
+		"success":   &successColor,
+		"warning":   &warningColor,
+		"error":     &errorColor,
+		"text":      &textColor,
+		"subtle":    &subtleColor,
+	}
+	for colorName := range overrides {
+		if _, ok := colors[colorName]; !ok {
+			return fmt.Errorf("unknown color %q", colorName)
+		}
+	}
+	for colorName, color := range colors {
+		value := palette[colorName]
+		if override, ok := overrides[colorName]; ok {
+			value = override
+		}
+		*color = lipgloss.Color(value)
+	}
+
+	titleStyle = lipgloss.NewStyle().Foreground(primaryColor).Bold(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(primaryColor).Padding(0, 1)
+	headerStyle = lipgloss.NewStyle().Foreground(secondaryColor).Bold(true).Margin(1, 0)
+	itemStyle = lipgloss.NewStyle().Foreground(textColor).Padding(0, 1)
+	selectedItemStyle = lipgloss.NewStyle().Foreground(primaryColor).Background(lipgloss.Color("#ffffff")).Bold(true).Padding(0, 1)
+	tagStyle = lipgloss.NewStyle().Foreground(accentColor).Background(lipgloss.Color("#e8f4fd")).Padding(0, 1).Margin(0, 1)
+	folderStyle = lipgloss.NewStyle().Foreground(successColor).Bold(true)
+	helpStyle = lipgloss.NewStyle().Foreground(subtleColor).Margin(1, 0)
+	return nil
+}
@@ -1,10 +1,15 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/2004-nikhil/quicknotes/internal/search"
+	"github.com/2004-nikhil/quicknotes/internal/store"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -22,6 +27,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.textArea.SetHeight(msg.Height - 8)
 		return m, nil
 
+	case editorFinishedMsg:
+		return m.updateEditorFinished(msg)
+
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" {
 			return m, tea.Quit
@@ -44,6 +52,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateTemplate(msg)
 		case inputDialogView:
 			return m.updateInputDialog(msg)
+		case noteMetaView:
+			return m.updateNoteMeta(msg)
+		case recoveryView:
+			return m.updateRecovery(msg)
+		case vaultView:
+			return m.updateVault(msg)
+		case historyView:
+			return m.updateHistory(msg)
+		case diffView:
+			return m.updateDiff(msg)
+		case trashView:
+			return m.updateTrash(msg)
+		case confirmView:
+			return m.updateConfirm(msg)
 		}
 	}
 
@@ -60,6 +82,7 @@ func (m model) updateMainMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			switch i.title {
 			case "📝 View Notes":
 				m.state = noteListView
+				m.filter = noteFilter{}
 				m = m.loadNoteList()
 			case "➕ New Note":
 				m.state = inputDialogView
@@ -81,6 +104,12 @@ func (m model) updateMainMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			case "📋 Templates":
 				m.state = templateView
 				m = m.loadTemplateList()
+			case "🗑️  Trash":
+				m.state = trashView
+				m = m.loadTrashList()
+			case "🗄️  Switch Vault":
+				m.state = vaultView
+				m = m.loadVaultList()
 			case "❌ Exit":
 				return m, tea.Quit
 			}
@@ -96,6 +125,10 @@ func (m model) updateMainMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m model) updateNoteList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "q", "esc":
+		if m.filter.kind != "" {
+			m.message, m.messageType = "", ""
+			return m.closeBrowse(), nil
+		}
 		m.state = mainMenuView
 		m = m.loadMainMenu()
 		m.message, m.messageType = "", ""
@@ -111,12 +144,38 @@ func (m model) updateNoteList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				}
 			}
 		}
+	case "e":
+		if i, ok := m.list.SelectedItem().(item); ok {
+			for _, note := range m.data.Notes {
+				if note.ID == i.id {
+					return m.openExternalEditor(note, note.Content)
+				}
+			}
+		}
+	case "m":
+		if i, ok := m.list.SelectedItem().(item); ok {
+			for _, note := range m.data.Notes {
+				if note.ID == i.id {
+					m.currentNote = &note
+					m = m.openNoteMeta(noteListView)
+					return m, nil
+				}
+			}
+		}
+	case "h":
+		if i, ok := m.list.SelectedItem().(item); ok {
+			return m.openHistory(i.id), nil
+		}
 	case "d":
 		if i, ok := m.list.SelectedItem().(item); ok {
-			m.data.Notes = removeNoteByID(m.data.Notes, i.id)
-			saveData(m.data)
-			m = m.loadNoteList()
-			m.message, m.messageType = "Note deleted successfully!", "success"
+			m = m.askConfirmation(confirmation{
+				action:  "trash_note",
+				noteID:  i.id,
+				title:   fmt.Sprintf("Delete note '%s'?", i.title),
+				details: []string{"The note will be moved to the trash, where it can be restored."},
+				options: []confirmOption{{label: "🗑️  Move to Trash", desc: "Delete the note", choice: "trash"}},
+			})
+			return m, nil
 		}
 	}
 	var cmd tea.Cmd
@@ -129,24 +188,19 @@ func (m model) updateNoteEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+s":
 		m.currentNote.Content = m.textArea.Value()
-		m.currentNote.UpdatedAt = time.Now()
-
-		found := false
-		for i, note := range m.data.Notes {
-			if note.ID == m.currentNote.ID {
-				m.data.Notes[i] = *m.currentNote
-				found = true
-				break
-			}
-		}
-		if !found {
-			m.data.Notes = append(m.data.Notes, *m.currentNote)
-		}
+		m = m.commitCurrentNote()
 
-		saveData(m.data)
+		var saved bool
+		if m, saved = m.saveData("Note saved successfully!"); !saved {
+			return m, nil
+		}
 		m.state = noteListView
 		m = m.loadNoteList()
-		m.message, m.messageType = "Note saved successfully!", "success"
+	case "ctrl+t":
+		m = m.openNoteMeta(noteEditView)
+		return m, nil
+	case "ctrl+o":
+		return m.openExternalEditor(*m.currentNote, m.textArea.Value())
 	case "esc":
 		m.state = noteListView
 		m = m.loadNoteList()
@@ -163,18 +217,27 @@ func (m model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "esc":
 		m.state = mainMenuView
 		m = m.loadMainMenu()
+	case "tab":
+		m.fuzzySearch = !m.fuzzySearch
+		return m, nil
 	case "enter":
 		query := m.textInput.Value()
-		results := m.searchNotes(query)
+		results, err := m.searchNotes(query)
+		if err != nil {
+			m.message, m.messageType = err.Error(), "error"
+			return m, nil
+		}
 
 		items := []list.Item{}
 		for _, note := range results {
-			desc := fmt.Sprintf("📁 %s | 🏷️ %s | %s", note.Folder, strings.Join(note.Tags, ", "), note.CreatedAt.Format("2006-01-02"))
-			items = append(items, item{title: note.Title, desc: desc, id: note.ID})
+			items = append(items, item{title: note.Title, desc: note.Summary(), id: note.ID})
 		}
 
 		m.list = m.createList()
 		m.list.Title = fmt.Sprintf("Search Results for: '%s'", query)
+		if m.fuzzySearch {
+			m.list.Title = fmt.Sprintf("Fuzzy Results for: '%s'", query)
+		}
 		m.list.SetItems(items)
 		m.state = noteListView
 	}
@@ -198,25 +261,56 @@ func (m model) updateFolderManage(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.textInput.SetValue("")
 			m.textInput.Placeholder = "Enter folder name..."
 			m.textInput.Focus()
+		} else if ok {
+			return m.browse("folder", m.data.Folders[i.id]), nil
 		}
-	case "d":
-		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 && i.title != "General" && i.title != "Work" && i.title != "Personal" {
-			folderNameToDelete := i.title
-			var newFolders []string
-			for _, folder := range m.data.Folders {
-				if folder != folderNameToDelete {
-					newFolders = append(newFolders, folder)
+	case " ":
+		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 {
+			folder := m.data.Folders[i.id]
+			if m.hasSubfolders(folder) {
+				if m.collapsed == nil {
+					m.collapsed = map[string]bool{}
 				}
+				m.collapsed[folder] = !m.collapsed[folder]
+				m = m.reloadList()
 			}
-			m.data.Folders = newFolders
-			saveData(m.data)
-			m = m.loadFolderList()
-			m.message, m.messageType = "Folder deleted!", "success"
-		} else {
-			_, ok := m.list.SelectedItem().(item)
-			if ok {
-				m.message, m.messageType = "Cannot delete default folders!", "error"
+			return m, nil
+		}
+	case "d":
+		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 {
+			folder := m.data.Folders[i.id]
+			if store.InFolder(m.data.DefaultFolder, folder) {
+				m.message, m.messageType = "Cannot delete the default folder! Make another folder the default (s) first.", "error"
+				return m, nil
+			}
+			m = m.askConfirmation(m.folderDeletion(folder))
+			return m, nil
+		}
+	case "r":
+		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 {
+			return m.openRename("rename_folder", m.data.Folders[i.id]), nil
+		}
+	case "v":
+		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 {
+			m = m.askConfirmation(m.moveConfirmation(m.data.Folders[i.id]))
+			return m, nil
+		}
+	case "s":
+		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 && m.data.Folders[i.id] != m.data.DefaultFolder {
+			folder := m.data.Folders[i.id]
+			if err := m.data.SetDefaultFolder(folder); err != nil {
+				m.message, m.messageType = err.Error(), "error"
+				return m, nil
 			}
+			m = m.reloadList()
+			m, _ = m.saveData(fmt.Sprintf("'%s' is now the default folder.", folder))
+			return m, nil
+		}
+	case "m":
+		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 {
+			folder := m.data.Folders[i.id]
+			m = m.askConfirmation(m.mergeConfirmation("merge_folder", folder, m.foldersOutside(folder), countNotesUnder(m.data.Notes, folder)))
+			return m, nil
 		}
 	}
 
@@ -239,21 +333,28 @@ func (m model) updateTagManage(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.textInput.SetValue("")
 			m.textInput.Placeholder = "Enter tag name..."
 			m.textInput.Focus()
+		} else if ok {
+			return m.browse("tag", m.data.Tags[i.id]), nil
 		}
 	case "d":
 		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 {
-			tagToDelete := i.title
-			var newTags []string
-			for _, tag := range m.data.Tags {
-				if tag != tagToDelete {
-					newTags = append(newTags, tag)
-				}
-			}
-			m.data.Tags = newTags
-			saveData(m.data)
-			m = m.loadTagList()
-			m.message = "Tag deleted!"
-			m.messageType = "success"
+			m = m.askConfirmation(confirmation{
+				action:  "delete_tag",
+				name:    i.title,
+				title:   fmt.Sprintf("Delete tag '%s'?", i.title),
+				details: []string{fmt.Sprintf("Tag '%s' is used by %d notes.", i.title, countNotesWithTag(m.data.Notes, i.title))},
+				options: []confirmOption{{label: "🗑️  Delete Tag", desc: "Remove the tag from the tag list and from every note", choice: "delete"}},
+			})
+			return m, nil
+		}
+	case "r":
+		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 {
+			return m.openRename("rename_tag", i.title), nil
+		}
+	case "m":
+		if i, ok := m.list.SelectedItem().(item); ok && i.id >= 0 {
+			m = m.askConfirmation(m.mergeConfirmation("merge_tag", i.title, m.data.Tags, countNotesWithTag(m.data.Notes, i.title)))
+			return m, nil
 		}
 	}
 	var cmd tea.Cmd
@@ -270,16 +371,10 @@ func (m model) updateTemplate(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "enter":
 		if i, ok := m.list.SelectedItem().(item); ok && i.id < len(m.data.Templates) {
 			template := m.data.Templates[i.id]
-			m.currentNote = &Note{
-				ID:        m.data.NextID,
-				Title:     template.Name,
-				Content:   template.Content,
-				Tags:      append([]string{}, template.Tags...),
-				Folder:    "General",
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			}
-			m.data.NextID++
+			note := m.data.NewNote(template.Name, m.data.DefaultFolder)
+			note.Content = template.Content
+			note.Tags = append(note.Tags, template.Tags...)
+			m.currentNote = &note
 			m.state = noteEditView
 			m.textArea.SetValue(template.Content)
 			m.textArea.Focus()
@@ -300,9 +395,17 @@ func (m model) updateInputDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m = m.loadFolderList()
 		case tagManageView:
 			m = m.loadTagList()
+		case noteMetaView:
+			m = m.loadNoteMeta()
+		case vaultView:
+			m = m.loadVaultList()
 		default:
 			m = m.loadMainMenu()
 		}
+		if m.renameFrom != "" {
+			m.list.Select(m.renameIndex)
+			m.renameFrom = ""
+		}
 	case "enter":
 		input := strings.TrimSpace(m.textInput.Value())
 		if input == "" {
@@ -311,33 +414,552 @@ func (m model) updateInputDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		switch m.inputMode {
 		case "note_title":
-			m.currentNote = &Note{
-				ID:        m.data.NextID,
-				Title:     input,
-				Folder:    "General",
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			}
-			m.data.NextID++
+			note := m.data.NewNote(input, m.data.DefaultFolder)
+			m.currentNote = &note
 			m.state = noteEditView
 			m.textArea.SetValue("")
 			m.textArea.Focus()
 		case "folder_name":
-			m.data.Folders = append(m.data.Folders, input)
-			saveData(m.data)
+			if err := m.data.AddFolder(input); err != nil {
+				m.message, m.messageType = err.Error(), "error"
+				return m, nil
+			}
+			input, _ = store.CleanFolderPath(input)
 			m.state = folderManageView
 			m = m.loadFolderList()
-			m.message, m.messageType = fmt.Sprintf("Folder '%s' created!", input), "success"
+			m, _ = m.saveData(fmt.Sprintf("Folder '%s' created!", input))
 		case "tag_name":
 			m.data.Tags = append(m.data.Tags, input)
-			saveData(m.data)
 			m.state = tagManageView
 			m = m.loadTagList()
-			m.message, m.messageType = fmt.Sprintf("Tag '%s' created!", input), "success"
+			m, _ = m.saveData(fmt.Sprintf("Tag '%s' created!", input))
+		case "meta_tag_name":
+			if !slices.Contains(m.metaTags, input) {
+				m.metaTags = append(m.metaTags, input)
+			}
+			m.state = noteMetaView
+			m = m.loadNoteMeta()
+			if slices.Contains(m.data.Tags, input) {
+				m.message, m.messageType = fmt.Sprintf("Tag '%s' added!", input), "success"
+			} else {
+				m.data.Tags = append(m.data.Tags, input)
+				m, _ = m.saveData(fmt.Sprintf("Tag '%s' added!", input))
+			}
+		case "vault_name":
+			if err := m.options.Vaults.Create(input); err != nil {
+				m.message, m.messageType = fmt.Sprintf("Could not create vault: %v", err), "error"
+				return m, nil
+			}
+			m.state = vaultView
+			m = m.switchVault(input)
+		case "rename_folder", "rename_tag":
+			return m.rename(input), nil
 		}
 	}
 
 	var cmd tea.Cmd
 	m.textInput, cmd = m.textInput.Update(msg)
 	return m, cmd
-}
\ No newline at end of file
+}
+
+// openNoteMeta opens the tag/folder panel for the current note.
+func (m model) openNoteMeta(returnTo viewState) model {
+	m.metaTags = append([]string{}, m.currentNote.Tags...)
+	m.metaFolder = m.currentNote.Folder
+	m.metaField = "tags"
+	m.metaReturn = returnTo
+	m.state = noteMetaView
+	m.message, m.messageType = "", ""
+	return m.loadNoteMeta()
+}
+
+// updateNoteMeta handles keypresses in the note metadata panel.
+func (m model) updateNoteMeta(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = m.metaReturn
+		if m.state == noteListView {
+			m = m.loadNoteList()
+		}
+		return m, nil
+	case "tab":
+		if m.metaField == "tags" {
+			m.metaField = "folder"
+		} else {
+			m.metaField = "tags"
+		}
+		m = m.loadNoteMeta()
+		return m, nil
+	case " ", "enter":
+		i, ok := m.list.SelectedItem().(item)
+		if !ok {
+			break
+		}
+		if i.id == -1 {
+			m.state = inputDialogView
+			m.inputMode = "meta_tag_name"
+			m.previousState = noteMetaView
+			m.textInput.SetValue("")
+			m.textInput.Placeholder = "Enter tag name..."
+			m.textInput.Focus()
+			return m, nil
+		}
+		index := m.list.Index()
+		if m.metaField == "tags" {
+			tag := m.metaTagOptions()[i.id]
+			if slices.Contains(m.metaTags, tag) {
+				m.metaTags = slices.DeleteFunc(m.metaTags, func(t string) bool { return t == tag })
+			} else {
+				m.metaTags = append(m.metaTags, tag)
+			}
+		} else {
+			m.metaFolder = m.data.Folders[i.id]
+		}
+		m = m.loadNoteMeta()
+		m.list.Select(index)
+		return m, nil
+	case "ctrl+s":
+		m.currentNote.Tags = append([]string{}, m.metaTags...)
+		m.currentNote.Folder = m.metaFolder
+		if note, ok := m.data.Note(m.currentNote.ID); ok {
+			note.Tags = m.currentNote.Tags
+			note.Folder = m.currentNote.Folder
+			note.UpdatedAt = time.Now()
+			m.data.RecordEdit(note)
+		}
+		m.state = m.metaReturn
+		if m.state == noteListView {
+			m = m.loadNoteList()
+		}
+		m, _ = m.saveData("Note details updated!")
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// updateTrash handles keypresses in the trash view.
+func (m model) updateTrash(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.state = mainMenuView
+		m = m.loadMainMenu()
+		return m, nil
+	case "r":
+		if i, ok := m.list.SelectedItem().(item); ok {
+			m.data.RestoreNote(i.id)
+			m = m.reloadList()
+			m, _ = m.saveData(fmt.Sprintf("Restored '%s'", i.title))
+			return m, nil
+		}
+	case "d":
+		if i, ok := m.list.SelectedItem().(item); ok {
+			m = m.askConfirmation(confirmation{
+				action:  "purge_note",
+				noteID:  i.id,
+				title:   fmt.Sprintf("Permanently delete '%s'?", i.title),
+				details: []string{"The note and its revision history will be gone for good. This cannot be undone."},
+				options: []confirmOption{{label: "❌ Delete Permanently", desc: "Delete the note and its history", choice: "purge"}},
+			})
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// trashNote moves a note to the trash.
+func (m model) trashNote(id int) model {
+	m.data.TrashNote(id)
+	m = m.reloadList()
+	m, _ = m.saveData("Note moved to the trash. Restore it from 🗑️ Trash on the main menu.")
+	return m
+}
+
+// purgeNote deletes a note and its history for good.
+func (m model) purgeNote(id int) model {
+	m.data.DeleteNote(id)
+	m = m.reloadList()
+	m, _ = m.saveData("Note permanently deleted.")
+	return m
+}
+
+// openRename opens the input dialog to rename a folder or tag, prefilled
+// with its current name.
+func (m model) openRename(mode, name string) model {
+	m.renameFrom, m.renameIndex = name, m.list.Index()
+	m.previousState = m.state
+	m.state = inputDialogView
+	m.inputMode = mode
+	m.textInput.SetValue(name)
+	m.textInput.Placeholder = "Enter new name..."
+	m.textInput.CursorEnd()
+	m.textInput.Focus()
+	return m
+}
+
+// rename applies the rename entered in the input dialog.
+func (m model) rename(to string) model {
+	from := m.renameFrom
+	var changed int
+	var err error
+	switch {
+	case to == from:
+	case m.inputMode == "rename_folder":
+		changed, err = m.data.RenameFolder(from, to)
+	default:
+		changed, err = m.data.RenameTag(from, to)
+	}
+	if err != nil {
+		m.message, m.messageType = fmt.Sprintf("%v; use merge (m) to combine them.", err), "error"
+		return m
+	}
+	m.state = m.previousState
+	m = m.loadList()
+	m.list.Select(m.renameIndex)
+	m.renameFrom = ""
+	if to != from {
+		m, _ = m.saveData(fmt.Sprintf("Renamed '%s' to '%s', %d notes updated.", from, to, changed))
+	}
+	return m
+}
+
+// mergeConfirmation offers to merge a folder or tag into each of the
+// others.
+func (m model) mergeConfirmation(action, name string, names []string, notes int) confirmation {
+	kind := "folder"
+	if action == "merge_tag" {
+		kind = "tag"
+	}
+	c := confirmation{
+		action:  action,
+		name:    name,
+		title:   fmt.Sprintf("Merge %s '%s' into…", kind, name),
+		details: []string{fmt.Sprintf("Its %d notes will be updated and '%s' will be removed.", notes, name)},
+	}
+	for _, target := range names {
+		if target != name {
+			c.options = append(c.options, confirmOption{label: "🔀 " + target, desc: fmt.Sprintf("Merge '%s' into '%s'", name, target), choice: target})
+		}
+	}
+	return c
+}
+
+// merge folds a folder or tag into another one.
+func (m model) merge(action, from, into string) model {
+	var changed int
+	var err error
+	if action == "merge_folder" {
+		changed, err = m.data.MergeFolder(from, into)
+	} else {
+		changed, err = m.data.MergeTag(from, into)
+	}
+	if err != nil {
+		m.message, m.messageType = err.Error(), "error"
+		return m
+	}
+	m = m.reloadList()
+	m, _ = m.saveData(fmt.Sprintf("Merged '%s' into '%s', %d notes updated.", from, into, changed))
+	return m
+}
+
+// hasSubfolders reports whether a folder contains other folders.
+func (m model) hasSubfolders(folder string) bool {
+	for _, f := range m.data.Folders {
+		if f != folder && store.InFolder(f, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// foldersOutside lists the folders in tree order, leaving out folder and
+// its subfolders.
+func (m model) foldersOutside(folder string) []string {
+	var folders []string
+	for _, f := range m.data.FolderTree() {
+		if !store.InFolder(f, folder) {
+			folders = append(folders, f)
+		}
+	}
+	return folders
+}
+
+// moveConfirmation offers to move a folder, with its subfolders and
+// notes, to the top level or into any folder outside it.
+func (m model) moveConfirmation(name string) confirmation {
+	c := confirmation{
+		action:  "move_folder",
+		name:    name,
+		title:   fmt.Sprintf("Move folder '%s' into…", name),
+		details: []string{fmt.Sprintf("Its subfolders and %d notes move along.", countNotesUnder(m.data.Notes, name))},
+	}
+	parent := store.FolderParent(name)
+	if parent != "" {
+		c.options = append(c.options, confirmOption{label: "⬆️  Top level", desc: fmt.Sprintf("Move '%s' out of '%s'", store.FolderName(name), parent), choice: ""})
+	}
+	for _, target := range m.foldersOutside(name) {
+		if target != parent {
+			c.options = append(c.options, confirmOption{label: "📁 " + target, desc: fmt.Sprintf("Move '%s' into '%s'", store.FolderName(name), target), choice: target})
+		}
+	}
+	return c
+}
+
+// moveFolder moves a folder and everything in it under parent, or to the
+// top level if parent is "".
+func (m model) moveFolder(name, parent string) model {
+	changed, err := m.data.MoveFolder(name, parent)
+	if err != nil {
+		m.message, m.messageType = err.Error(), "error"
+		return m
+	}
+	m = m.reloadList()
+	m, _ = m.saveData(fmt.Sprintf("Moved '%s', %d notes updated.", name, changed))
+	return m
+}
+
+// folderDeletion describes deleting a folder: its notes can be moved to
+// another folder, the default one first, or to the trash.
+func (m model) folderDeletion(name string) confirmation {
+	c := confirmation{
+		action: "delete_folder",
+		name:   name,
+		title:  fmt.Sprintf("Delete folder '%s'?", name),
+	}
+	filed := 0
+	for _, note := range m.data.Notes {
+		if store.InFolder(note.Folder, name) {
+			filed++
+		}
+	}
+	live := countNotesUnder(m.data.Notes, name)
+	c.details = append(c.details, fmt.Sprintf("Folder '%s' contains %d notes.", name, live))
+	if subfolders := len(m.data.Folders) - len(m.foldersOutside(name)) - 1; subfolders > 0 {
+		c.details = append(c.details, fmt.Sprintf("Its %d subfolders are deleted too.", subfolders))
+	}
+	if filed > live {
+		c.details = append(c.details, fmt.Sprintf("%d more notes from it are in the trash.", filed-live))
+	}
+	if filed == 0 {
+		c.options = append(c.options, confirmOption{label: "🗑️  Delete Folder", desc: "Remove the empty folder", choice: "move:" + m.data.DefaultFolder})
+		return c
+	}
+
+	targets := []string{m.data.DefaultFolder}
+	for _, folder := range m.foldersOutside(name) {
+		if folder != m.data.DefaultFolder {
+			targets = append(targets, folder)
+		}
+	}
+	for _, folder := range targets {
+		c.options = append(c.options, confirmOption{
+			label:  "📦 Move notes to " + folder,
+			desc:   fmt.Sprintf("Delete the folder and refile its %d notes", filed),
+			choice: "move:" + folder,
+		})
+	}
+	if live > 0 {
+		c.options = append(c.options, confirmOption{
+			label:  "🗑️  Move notes to the trash",
+			desc:   fmt.Sprintf("Delete the folder and its notes; restored notes go to %s", m.data.DefaultFolder),
+			choice: "trash",
+		})
+	}
+	return c
+}
+
+// deleteFolder removes a folder, moving its notes to another folder
+// ("move:<folder>") or to the trash ("trash").
+func (m model) deleteFolder(name, choice string) model {
+	var message string
+	var err error
+	if target, ok := strings.CutPrefix(choice, "move:"); ok {
+		var moved int
+		moved, err = m.data.DeleteFolder(name, target)
+		message = fmt.Sprintf("Folder deleted, %d notes moved to %s.", moved, target)
+	} else {
+		var trashed int
+		trashed, err = m.data.TrashFolder(name)
+		message = fmt.Sprintf("Folder deleted, %d notes moved to the trash.", trashed)
+	}
+	if err != nil {
+		m.message, m.messageType = err.Error(), "error"
+		return m
+	}
+	m = m.reloadList()
+	m, _ = m.saveData(message)
+	return m
+}
+
+// deleteTag removes a tag from the tag list and from every note.
+func (m model) deleteTag(name string) model {
+	stripped := m.data.DeleteTag(name)
+	m = m.reloadList()
+	m, _ = m.saveData(fmt.Sprintf("Tag deleted and removed from %d notes.", stripped))
+	return m
+}
+
+// commitCurrentNote copies the note being edited into the notebook,
+// adding it if it is new, and records a revision. The caller saves the
+// notebook afterwards.
+func (m model) commitCurrentNote() model {
+	m.currentNote.UpdatedAt = time.Now()
+	m.data.RecordEdit(*m.currentNote)
+	return m
+}
+
+// saveData persists the notebook and reports the outcome in the status
+// line. It returns false when the write failed.
+func (m model) saveData(success string) (model, bool) {
+	err := m.store.Save(m.data)
+	if m.index != nil {
+		m.index.Sync(m.data.LiveNotes())
+	}
+	var conflict *store.ConflictError
+	if errors.As(err, &conflict) {
+		m.message, m.messageType = fmt.Sprintf("Saved, but another QuickNotes window changed the same notes: %v", err), "warning"
+		return m, true
+	}
+	if err != nil {
+		m.message, m.messageType = fmt.Sprintf("Could not save notes: %v", err), "error"
+		return m, false
+	}
+	m.message, m.messageType = success, "success"
+	return m, true
+}
+
+// startRecovery offers to restore one of the backups of an unreadable
+// notebook instead of silently starting over. The notebook is only moved
+// aside once the user has picked what to replace it with.
+func (m model) startRecovery(rec store.Recoverer, loadErr error) model {
+	backups, err := rec.Backups()
+	if err != nil {
+		backups = nil
+	}
+	m.recoverer = rec
+	m.loadErr = loadErr
+	m.backups = backups
+	m.state = recoveryView
+	m = m.loadRecoveryList()
+	return m
+}
+
+// updateRecovery handles keypresses in the startup recovery screen.
+func (m model) updateRecovery(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		i, ok := m.list.SelectedItem().(item)
+		if !ok {
+			break
+		}
+		if i.id == -2 {
+			return m, tea.Quit
+		}
+		quarantined, err := m.recoverer.Quarantine()
+		if err != nil {
+			m.message, m.messageType = fmt.Sprintf("Could not move the unreadable file aside: %v", err), "error"
+			return m, nil
+		}
+		if quarantined != "" {
+			m.quarantined = quarantined
+		}
+		var success string
+		if i.id == -1 {
+			if err := m.recoverer.Reset(); err != nil {
+				m.message, m.messageType = fmt.Sprintf("Could not start a new notebook: %v", err), "error"
+				return m, nil
+			}
+			success = "Started a new notebook."
+		} else {
+			backup := m.backups[i.id]
+			if err := m.recoverer.Restore(backup); err != nil {
+				m.message, m.messageType = fmt.Sprintf("Could not restore backup: %v", err), "error"
+				return m, nil
+			}
+			success = fmt.Sprintf("Restored %d notes from backup.", backup.Notes)
+		}
+		if m.quarantined != "" {
+			success += fmt.Sprintf(" The unreadable file was kept as %s.", m.quarantined)
+		}
+		data, err := m.store.Load()
+		if err != nil {
+			m.message, m.messageType = fmt.Sprintf("Could not load notes: %v", err), "error"
+			return m, nil
+		}
+		m.data = data
+		m.index = search.NewIndex(data.LiveNotes())
+		m.state = mainMenuView
+		m = m.loadMainMenu()
+		m.message, m.messageType = success, "success"
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// updateVault handles keypresses in the vault switcher.
+func (m model) updateVault(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.state = mainMenuView
+		m = m.loadMainMenu()
+	case "enter":
+		i, ok := m.list.SelectedItem().(item)
+		if !ok {
+			break
+		}
+		if i.id == -1 {
+			m.state = inputDialogView
+			m.inputMode = "vault_name"
+			m.previousState = vaultView
+			m.textInput.SetValue("")
+			m.textInput.Placeholder = "Enter vault name..."
+			m.textInput.Focus()
+			return m, nil
+		}
+		m = m.switchVault(m.options.Vaults.Names()[i.id])
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// switchVault makes the named vault the open notebook, staying on the
+// vault list if it cannot be opened.
+func (m model) switchVault(name string) model {
+	st, err := m.options.Vaults.Open(name)
+	if err != nil {
+		m = m.loadVaultList()
+		m.message, m.messageType = fmt.Sprintf("Could not open vault: %v", err), "error"
+		return m
+	}
+	switched := m
+	switched.options.Vault = name
+	switched, err = switched.openStore(st)
+	if err != nil {
+		closeStore(st)
+		m = m.loadVaultList()
+		m.message, m.messageType = fmt.Sprintf("Could not open vault: %v", err), "error"
+		return m
+	}
+	if switched.state == mainMenuView {
+		switched.message, switched.messageType = fmt.Sprintf("Switched to vault '%s'", name), "success"
+	}
+	closeStore(m.store)
+	return switched
+}
+
+// closeStore releases stores that hold resources, such as a database.
+func closeStore(st store.Store) {
+	if c, ok := st.(io.Closer); ok {
+		c.Close()
+	}
+}
@@ -2,8 +2,11 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/2004-nikhil/quicknotes/internal/search"
+	"github.com/2004-nikhil/quicknotes/internal/store"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -35,18 +38,34 @@ func (m model) View() string {
 	}
 
 	switch m.state {
-	case mainMenuView, noteListView, folderManageView, tagManageView, templateView:
+	case mainMenuView, noteListView, folderManageView, tagManageView, templateView, vaultView, historyView, trashView:
 		content = m.list.View()
 		// Add contextual help text
 		switch m.state {
 		case noteListView:
-			content += "\n" + helpStyle.Render("Enter: edit, d: delete, q: back to menu")
+			back := "q: back to menu"
+			if m.filter.kind != "" {
+				back = fmt.Sprintf("Esc: back to %ss", m.filter.kind)
+			}
+			content += "\n" + helpStyle.Render("Enter: edit, e: open in $EDITOR, m: tags & folder, h: history, d: delete, "+back)
 		case folderManageView:
-			content += "\n" + helpStyle.Render("Enter: select/create, d: delete, q: back to menu")
+			content += "\n" + helpStyle.Render("Enter: open notes/create, space: fold, r: rename, v: move, m: merge, s: make default, d: delete, q: back to menu")
 		case tagManageView:
-			content += "\n" + helpStyle.Render("Enter: select/create, d: delete, q: back to menu")
+			content += "\n" + helpStyle.Render("Enter: open notes/create, r: rename, m: merge, d: delete, q: back to menu")
 		case templateView:
 			content += "\n" + helpStyle.Render("Enter: use template, q: back to menu")
+		case vaultView:
+			content += "\n" + helpStyle.Render("Enter: switch/create, q: back to menu")
+		case trashView:
+			if m.options.TrashRetention > 0 {
+				content += fmt.Sprintf("\nNotes are deleted for good %d days after they were moved here.\n", int(m.options.TrashRetention.Hours()/24))
+			}
+			content += "\n" + helpStyle.Render("r: restore, d: delete permanently, q: back to menu")
+		case historyView:
+			if len(m.revisions) == 0 {
+				content += "\nNo revisions yet. A revision is recorded every time the note is saved.\n"
+			}
+			content += "\n" + helpStyle.Render("Enter: diff with previous/marked, Space: mark for diff, r: restore, q: back to notes")
 		default: // mainMenuView
 			content += "\n" + helpStyle.Render("Use ↑/↓ to navigate, Enter to select, Ctrl+C to quit")
 		}
@@ -57,12 +76,21 @@ func (m model) View() string {
 		}
 		content = headerStyle.Render(title) + "\n\n"
 		content += m.textArea.View()
-		content += "\n" + helpStyle.Render("Ctrl+S: save, Esc: cancel")
+		content += "\n" + helpStyle.Render("Ctrl+S: save, Ctrl+T: tags & folder, Ctrl+O: open in $EDITOR, Esc: cancel")
 	case searchView:
 		content = headerStyle.Render("Search Notes") + "\n\n"
-		content += "Enter search query:\n"
+		if m.fuzzySearch {
+			content += "Enter search query (fuzzy, typos allowed):\n"
+		} else {
+			content += "Enter search query:\n"
+		}
 		content += m.textInput.View()
-		content += "\n\n" + helpStyle.Render("Enter: search, Esc: back to menu")
+		if m.fuzzySearch {
+			content += "\n\n" + helpStyle.Render("Titles and tags match by letters in order, content words with a typo or two")
+		} else {
+			content += "\n\n" + helpStyle.Render(`Filters: tag:work folder:Work title:"..." created:>2025-01-01 updated:<7d, AND/OR/NOT, -word, (...)`)
+		}
+		content += "\n" + helpStyle.Render("Enter: search, Tab: toggle fuzzy mode, Esc: back to menu")
 	case inputDialogView:
 		var title string
 		switch m.inputMode {
@@ -70,12 +98,56 @@ func (m model) View() string {
 			title = "New Note"
 		case "folder_name":
 			title = "New Folder"
-		case "tag_name":
+		case "tag_name", "meta_tag_name":
 			title = "New Tag"
+		case "vault_name":
+			title = "New Vault"
+		case "rename_folder":
+			title = fmt.Sprintf("Rename Folder '%s'", m.renameFrom)
+		case "rename_tag":
+			title = fmt.Sprintf("Rename Tag '%s'", m.renameFrom)
 		}
 		content = headerStyle.Render(title) + "\n\n"
 		content += m.textInput.View()
-		content += "\n\n" + helpStyle.Render("Enter: create, Esc: cancel")
+		if m.renameFrom != "" {
+			content += "\n\n" + helpStyle.Render("Enter: rename, Esc: cancel")
+		} else {
+			content += "\n\n" + helpStyle.Render("Enter: create, Esc: cancel")
+		}
+	case recoveryView:
+		content = headerStyle.Render("⚠️  Your notes could not be loaded") + "\n"
+		content += fmt.Sprintf("%v\n\n", m.loadErr)
+		content += "Restoring a backup or starting over keeps the unreadable file next to it, with a .corrupt suffix, so nothing is lost.\n"
+		if len(m.backups) > 0 {
+			content += "Pick a backup to restore, or start over with an empty notebook.\n\n"
+		} else {
+			content += "No usable backup was found. You can start over with an empty notebook.\n\n"
+		}
+		content += m.list.View()
+		content += "\n" + helpStyle.Render("Enter: select, Ctrl+C: quit")
+	case confirmView:
+		content = headerStyle.Render("⚠️  "+m.confirm.title) + "\n"
+		for _, detail := range m.confirm.details {
+			content += detail + "\n"
+		}
+		content += "\n" + m.list.View()
+		content += "\n" + helpStyle.Render("Enter: choose, Esc: cancel")
+	case diffView:
+		content = headerStyle.Render(m.diffTitle) + "\n\n"
+		content += m.diffPort.View()
+		content += "\n" + helpStyle.Render("↑/↓/PgUp/PgDn: scroll, q: back to history")
+	case noteMetaView:
+		content = headerStyle.Render(fmt.Sprintf("Details: %s", m.currentNote.Title)) + "\n"
+		content += folderStyle.Render("📁 "+m.metaFolder) + " "
+		for _, tag := range m.metaTags {
+			content += tagStyle.Render(tag)
+		}
+		content += "\n\n" + m.list.View()
+		if m.metaField == "tags" {
+			content += "\n" + helpStyle.Render("Space/Enter: toggle tag, Tab: choose folder, Ctrl+S: save, Esc: cancel")
+		} else {
+			content += "\n" + helpStyle.Render("Space/Enter: choose folder, Tab: choose tags, Ctrl+S: save, Esc: cancel")
+		}
 	}
 
 	finalContent.WriteString(content)
@@ -102,10 +174,17 @@ func (m model) loadMainMenu() model {
 		item{title: "📁 Manage Folders", desc: "Create and organize folders"},
 		item{title: "🏷️  Manage Tags", desc: "Create and organize tags"},
 		item{title: "📋 Templates", desc: "Use pre-built note templates"},
-		item{title: "❌ Exit", desc: "Quit the application"},
+		item{title: "🗑️  Trash", desc: "Restore or permanently delete deleted notes"},
 	}
+	if m.options.Vaults != nil {
+		items = append(items, item{title: "🗄️  Switch Vault", desc: fmt.Sprintf("Current vault: %s", m.options.Vault)})
+	}
+	items = append(items, item{title: "❌ Exit", desc: "Quit the application"})
 	m.list = m.createList()
 	m.list.Title = "QuickNotes - Beautiful CLI Note Taking"
+	if m.options.Vaults != nil {
+		m.list.Title = fmt.Sprintf("QuickNotes - %s vault", m.options.Vault)
+	}
 	m.list.SetItems(items)
 	return m
 }
@@ -113,12 +192,13 @@ func (m model) loadMainMenu() model {
 // loadNoteList prepares the list for the note list view.
 func (m model) loadNoteList() model {
 	items := []list.Item{}
-	for _, note := range m.data.Notes {
-		desc := fmt.Sprintf("📁 %s | 🏷️ %s | %s", note.Folder, strings.Join(note.Tags, ", "), note.CreatedAt.Format("2006-01-02"))
-		items = append(items, item{title: note.Title, desc: desc, id: note.ID})
+	for _, note := range m.data.LiveNotes() {
+		if m.filter.matches(note) {
+			items = append(items, item{title: note.Title, desc: note.Summary(), id: note.ID})
+		}
 	}
 	m.list = m.createList()
-	m.list.Title = "Your Notes"
+	m.list.Title = m.filter.title()
 	m.list.SetItems(items)
 	return m
 }
@@ -126,10 +206,30 @@ func (m model) loadNoteList() model {
 // loadFolderList prepares the list for the folder management view.
 func (m model) loadFolderList() model {
 	items := []list.Item{}
-	for i, folder := range m.data.Folders {
-		noteCount := countNotesInFolder(m.data.Notes, folder)
+	hidden := ""
+	for _, folder := range m.data.FolderTree() {
+		if hidden != "" && store.InFolder(folder, hidden) {
+			continue
+		}
+		hidden = ""
+		branch := "• "
+		if m.hasSubfolders(folder) {
+			branch = "▾ "
+			if m.collapsed[folder] {
+				branch = "▸ "
+				hidden = folder
+			}
+		}
+		title := strings.Repeat("  ", store.FolderDepth(folder)) + branch + store.FolderName(folder)
+		noteCount := countNotesUnder(m.data.Notes, folder)
 		desc := fmt.Sprintf("%d notes", noteCount)
-		items = append(items, item{title: folder, desc: desc, id: i})
+		if direct := countNotesInFolder(m.data.Notes, folder); direct != noteCount {
+			desc += fmt.Sprintf(" (%d here)", direct)
+		}
+		if folder == m.data.DefaultFolder {
+			desc += " · ⭐ default"
+		}
+		items = append(items, item{title: title, desc: desc, id: slices.Index(m.data.Folders, folder)})
 	}
 	items = append(items, item{title: "➕ Add New Folder", desc: "Create a new folder", id: -1})
 	m.list = m.createList()
@@ -167,17 +267,106 @@ func (m model) loadTemplateList() model {
 	return m
 }
 
-// searchNotes filters notes based on a query.
-func (m model) searchNotes(query string) []Note {
-	var results []Note
-	query = strings.ToLower(query)
+// loadTrashList prepares the list for the trash view, most recently
+// deleted first.
+func (m model) loadTrashList() model {
+	items := []list.Item{}
+	for _, note := range m.data.TrashedNotes() {
+		desc := fmt.Sprintf("🗑️  Deleted %s | 📁 %s", note.DeletedAt.Format("2006-01-02 15:04"), note.Folder)
+		items = append(items, item{title: note.Title, desc: desc, id: note.ID})
+	}
+	m.list = m.createList()
+	m.list.Title = "Trash"
+	m.list.SetItems(items)
+	return m
+}
 
-	for _, note := range m.data.Notes {
-		if strings.Contains(strings.ToLower(note.Title), query) ||
-			strings.Contains(strings.ToLower(note.Content), query) ||
-			containsTag(note.Tags, query) {
-			results = append(results, note)
+// loadNoteMeta prepares the list for the note metadata panel.
+func (m model) loadNoteMeta() model {
+	items := []list.Item{}
+	if m.metaField == "tags" {
+		for i, tag := range m.metaTagOptions() {
+			mark := "[ ]"
+			if slices.Contains(m.metaTags, tag) {
+				mark = "[x]"
+			}
+			desc := fmt.Sprintf("%d notes", countNotesWithTag(m.data.Notes, tag))
+			items = append(items, item{title: mark + " " + tag, desc: desc, id: i})
 		}
+		items = append(items, item{title: "➕ Add New Tag", desc: "Create a tag and assign it", id: -1})
+	} else {
+		for _, folder := range m.data.FolderTree() {
+			i := slices.Index(m.data.Folders, folder)
+			mark := "○"
+			if folder == m.metaFolder {
+				mark = "●"
+			}
+			desc := fmt.Sprintf("%d notes", countNotesInFolder(m.data.Notes, folder))
+			items = append(items, item{title: mark + " " + folder, desc: desc, id: i})
+		}
+	}
+	m.list = m.createList()
+	m.list.SetHeight(m.height - 8)
+	if m.metaField == "tags" {
+		m.list.Title = "Tags"
+	} else {
+		m.list.Title = "Folder"
 	}
-	return results
-}
\ No newline at end of file
+	m.list.SetItems(items)
+	return m
+}
+
+// metaTagOptions lists the known tags followed by any the note carries that
+// are not registered yet (e.g. tags that came from a template).
+func (m model) metaTagOptions() []string {
+	options := append([]string{}, m.data.Tags...)
+	for _, tag := range m.metaTags {
+		if !slices.Contains(options, tag) {
+			options = append(options, tag)
+		}
+	}
+	return options
+}
+
+// loadVaultList prepares the list for the vault switcher.
+func (m model) loadVaultList() model {
+	items := []list.Item{}
+	for i, name := range m.options.Vaults.Names() {
+		desc := "Switch to this vault"
+		if name == m.options.Vault {
+			desc = "Currently open"
+		}
+		items = append(items, item{title: name, desc: desc, id: i})
+	}
+	items = append(items, item{title: "➕ New Vault", desc: "Create a new, empty vault", id: -1})
+	m.list = m.createList()
+	m.list.Title = "Vaults"
+	m.list.SetItems(items)
+	return m
+}
+
+// loadRecoveryList prepares the list for the startup recovery screen.
+func (m model) loadRecoveryList() model {
+	items := []list.Item{}
+	for i, backup := range m.backups {
+		title := fmt.Sprintf("♻️  Restore backup from %s", backup.ModTime.Format("2006-01-02 15:04"))
+		desc := fmt.Sprintf("%d notes | %s", backup.Notes, backup.Path)
+		items = append(items, item{title: title, desc: desc, id: i})
+	}
+	items = append(items, item{title: "🆕 Start Empty", desc: "Begin a new notebook with the default folders and tags", id: -1})
+	items = append(items, item{title: "❌ Exit", desc: "Quit without changing anything", id: -2})
+	m.list = m.createList()
+	m.list.SetHeight(m.height - 12)
+	m.list.Title = "Recover Notes"
+	m.list.SetItems(items)
+	return m
+}
+
+// searchNotes filters notes based on a query; see search.Query for its
+// syntax. In fuzzy mode the query is plain text and may contain typos.
+func (m model) searchNotes(query string) ([]store.Note, error) {
+	if m.fuzzySearch {
+		return search.Fuzzy(m.index, m.data.LiveNotes(), query), nil
+	}
+	return search.Ranked(m.index, m.data.LiveNotes(), query)
+}